@@ -3,6 +3,7 @@ SELECT * FROM users
 WHERE id = ?;
 
 -- name: ListUsers :many
+-- @paginate keyset(created_at, id)
 SELECT * FROM users
 ORDER BY created_at DESC;
 
@@ -46,3 +47,12 @@ SELECT sqlc.embed(posts), sqlc.embed(users)
 FROM posts
 JOIN users ON users.id = posts.author_id
 ORDER BY posts.created_at DESC;
+
+-- name: ListUsersByIDs :many
+SELECT * FROM users
+WHERE id IN (sqlc.slice(ids))
+ORDER BY id;
+
+-- name: DeleteAuthorPosts :execrows
+DELETE FROM posts
+WHERE author_id = ? AND id IN (sqlc.slice(ids));
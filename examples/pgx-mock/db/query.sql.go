@@ -7,6 +7,8 @@ package db
 
 import (
 	"context"
+	"iter"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 )
@@ -30,6 +32,23 @@ func (q *CreateUserQuery) SQL() string {
 	return createUser
 }
 
+func (q *CreateUserQuery) QueryName() string {
+	return "CreateUser"
+}
+
+func (q *CreateUserQuery) QueryCmd() string {
+	return ":one"
+}
+
+func (q *CreateUserQuery) SourceFile() string {
+	return "query.sql"
+}
+
+// UsesPrimary makes RoutingExecutor run the query on the primary
+func (q *CreateUserQuery) UsesPrimary() bool {
+	return true
+}
+
 func (q *CreateUserQuery) Args() []any {
 	return []any{q.arg.Name, q.arg.Email}
 }
@@ -50,9 +69,30 @@ func (q *CreateUserQuery) Eval(ctx context.Context, arg CreateUserParams) (User,
 	return q.Result, nil
 }
 
+// With sets the arguments of the query without executing it, e.g. to queue it in a Batch
+func (q *CreateUserQuery) With(arg CreateUserParams) *CreateUserQuery {
+	q.arg = arg
+	return q
+}
+
 func NewCreateUserQuery(ex QueryExecutor) *CreateUserQuery {
 	return &CreateUserQuery{ex: ex}
 }
+
+func HandleCreateUser(fn func(arg CreateUserParams) (User, error)) FakeHandler {
+	return FakeHandler{
+		Name: "CreateUser",
+		Apply: func(q Query) error {
+			query := q.(*CreateUserQuery)
+			result, err := fn(query.arg)
+			if err != nil {
+				return err
+			}
+			query.SetResult(result)
+			return nil
+		},
+	}
+}
 func ExpectCreateUser(arg CreateUserParams, result User, err error) Step {
 	return Step{
 		SQL:  createUser,
@@ -64,6 +104,17 @@ func ExpectCreateUser(arg CreateUserParams, result User, err error) Step {
 	}
 }
 
+func ExpectCreateUserMatching(name, email ArgMatcher, result User, err error) Step {
+	return Step{
+		SQL:  createUser,
+		Args: []any{name, email},
+		Apply: func(q Query) error {
+			q.(*CreateUserQuery).SetResult(result)
+			return err
+		},
+	}
+}
+
 const deleteUser = `-- name: DeleteUser :execrows
 DELETE FROM users WHERE id = $1
 `
@@ -78,6 +129,23 @@ func (q *DeleteUserQuery) SQL() string {
 	return deleteUser
 }
 
+func (q *DeleteUserQuery) QueryName() string {
+	return "DeleteUser"
+}
+
+func (q *DeleteUserQuery) QueryCmd() string {
+	return ":execrows"
+}
+
+func (q *DeleteUserQuery) SourceFile() string {
+	return "query.sql"
+}
+
+// Timeout bounds the execution of the query, see "-- @timeout"
+func (q *DeleteUserQuery) Timeout() time.Duration {
+	return 2 * time.Second
+}
+
 func (q *DeleteUserQuery) Args() []any {
 	return []any{q.id}
 }
@@ -94,9 +162,30 @@ func (q *DeleteUserQuery) Eval(ctx context.Context, id int64) (int64, error) {
 	return q.RowsAffected, nil
 }
 
+// With sets the arguments of the query without executing it, e.g. to queue it in a Batch
+func (q *DeleteUserQuery) With(id int64) *DeleteUserQuery {
+	q.id = id
+	return q
+}
+
 func NewDeleteUserQuery(ex QueryExecutor) *DeleteUserQuery {
 	return &DeleteUserQuery{ex: ex}
 }
+
+func HandleDeleteUser(fn func(id int64) (int64, error)) FakeHandler {
+	return FakeHandler{
+		Name: "DeleteUser",
+		Apply: func(q Query) error {
+			query := q.(*DeleteUserQuery)
+			rowsAffected, err := fn(query.id)
+			if err != nil {
+				return err
+			}
+			query.SetRowsAffected(rowsAffected)
+			return nil
+		},
+	}
+}
 func ExpectDeleteUser(id int64, rowsAffected int64, err error) Step {
 	return Step{
 		SQL:  deleteUser,
@@ -108,6 +197,17 @@ func ExpectDeleteUser(id int64, rowsAffected int64, err error) Step {
 	}
 }
 
+func ExpectDeleteUserMatching(id ArgMatcher, rowsAffected int64, err error) Step {
+	return Step{
+		SQL:  deleteUser,
+		Args: []any{id},
+		Apply: func(q Query) error {
+			q.(*DeleteUserQuery).SetRowsAffected(rowsAffected)
+			return err
+		},
+	}
+}
+
 const getUser = `-- name: GetUser :one
 SELECT id, name, email FROM users WHERE id = $1
 `
@@ -122,6 +222,18 @@ func (q *GetUserQuery) SQL() string {
 	return getUser
 }
 
+func (q *GetUserQuery) QueryName() string {
+	return "GetUser"
+}
+
+func (q *GetUserQuery) QueryCmd() string {
+	return ":one"
+}
+
+func (q *GetUserQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *GetUserQuery) Args() []any {
 	return []any{q.id}
 }
@@ -142,9 +254,30 @@ func (q *GetUserQuery) Eval(ctx context.Context, id int64) (User, error) {
 	return q.Result, nil
 }
 
+// With sets the arguments of the query without executing it, e.g. to queue it in a Batch
+func (q *GetUserQuery) With(id int64) *GetUserQuery {
+	q.id = id
+	return q
+}
+
 func NewGetUserQuery(ex QueryExecutor) *GetUserQuery {
 	return &GetUserQuery{ex: ex}
 }
+
+func HandleGetUser(fn func(id int64) (User, error)) FakeHandler {
+	return FakeHandler{
+		Name: "GetUser",
+		Apply: func(q Query) error {
+			query := q.(*GetUserQuery)
+			result, err := fn(query.id)
+			if err != nil {
+				return err
+			}
+			query.SetResult(result)
+			return nil
+		},
+	}
+}
 func ExpectGetUser(id int64, result User, err error) Step {
 	return Step{
 		SQL:  getUser,
@@ -156,6 +289,17 @@ func ExpectGetUser(id int64, result User, err error) Step {
 	}
 }
 
+func ExpectGetUserMatching(id ArgMatcher, result User, err error) Step {
+	return Step{
+		SQL:  getUser,
+		Args: []any{id},
+		Apply: func(q Query) error {
+			q.(*GetUserQuery).SetResult(result)
+			return err
+		},
+	}
+}
+
 const listUsers = `-- name: ListUsers :many
 SELECT id, name, email FROM users ORDER BY id
 `
@@ -169,6 +313,18 @@ func (q *ListUsersQuery) SQL() string {
 	return listUsers
 }
 
+func (q *ListUsersQuery) QueryName() string {
+	return "ListUsers"
+}
+
+func (q *ListUsersQuery) QueryCmd() string {
+	return ":many"
+}
+
+func (q *ListUsersQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *ListUsersQuery) Args() []any {
 	return nil
 }
@@ -194,15 +350,66 @@ func (q *ListUsersQuery) Eval(ctx context.Context) ([]User, error) {
 	return q.Results, nil
 }
 
+// listUsersStream hands ListUsers rows to an Iter loop one at a time.
+type listUsersStream struct {
+	*ListUsersQuery
+	yield   func(User, error) bool
+	stopped bool
+}
+
+func (s *listUsersStream) ScanNext(row pgx.Row) (bool, error) {
+	var i User
+	if err := row.Scan(&i.ID, &i.Name, &i.Email); err != nil {
+		return false, err
+	}
+	s.stopped = !s.yield(i, nil)
+	return !s.stopped, nil
+}
+
+// SetResults yields results provided by a StubExecutor step.
+func (s *listUsersStream) SetResults(results []User) {
+	for _, r := range results {
+		if s.stopped = !s.yield(r, nil); s.stopped {
+			return
+		}
+	}
+}
+
+// Iter executes the query and yields rows as they are scanned. The rows are
+// closed when the loop finishes or breaks early.
+func (q *ListUsersQuery) Iter(ctx context.Context) iter.Seq2[User, error] {
+	return func(yield func(User, error) bool) {
+		s := &listUsersStream{ListUsersQuery: q, yield: yield}
+		if err := q.ex.Execute(ctx, s); err != nil && !s.stopped {
+			var zero User
+			yield(zero, err)
+		}
+	}
+}
+
 func NewListUsersQuery(ex QueryExecutor) *ListUsersQuery {
 	return &ListUsersQuery{ex: ex}
 }
+
+func HandleListUsers(fn func() ([]User, error)) FakeHandler {
+	return FakeHandler{
+		Name: "ListUsers",
+		Apply: func(q Query) error {
+			results, err := fn()
+			if err != nil {
+				return err
+			}
+			q.(interface{ SetResults([]User) }).SetResults(results)
+			return nil
+		},
+	}
+}
 func ExpectListUsers(results []User, err error) Step {
 	return Step{
 		SQL:  listUsers,
 		Args: nil,
 		Apply: func(q Query) error {
-			q.(*ListUsersQuery).SetResults(results)
+			q.(interface{ SetResults([]User) }).SetResults(results)
 			return err
 		},
 	}
@@ -2,9 +2,17 @@ package db_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/sqlc-dev/sqlc-gen-go/examples/pgx-mock/db"
 )
 
@@ -208,6 +216,302 @@ func TestIncompleteSteps(t *testing.T) {
 	}
 }
 
+// TestArgMatchers shows how to assert only on the arguments a test cares about
+func TestArgMatchers(t *testing.T) {
+	ctx := context.Background()
+
+	stub := db.NewStubExecutor(t,
+		db.ExpectCreateUserMatching(
+			db.Eq("Alice"),
+			db.ArgThat(func(arg any) bool { return strings.HasSuffix(arg.(string), "@test.com") }),
+			db.User{ID: 1, Name: "Alice"},
+			nil,
+		),
+		db.ExpectGetUserMatching(db.Any(), db.User{ID: 7, Name: "Bob"}, nil),
+		// Plain values and matchers can be mixed in Step.Args
+		db.Step{
+			SQL:  db.NewCreateUserQuery(nil).SQL(),
+			Args: []any{"Carol", db.Any()},
+			Apply: func(q db.Query) error {
+				q.(*db.CreateUserQuery).SetResult(db.User{ID: 3, Name: "Carol"})
+				return nil
+			},
+		},
+	)
+
+	if _, err := db.NewCreateUserQuery(stub).Eval(ctx, db.CreateUserParams{Name: "Alice", Email: "alice@test.com"}); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	user, err := db.NewGetUserQuery(stub).Eval(ctx, 7)
+	if err != nil || user.Name != "Bob" {
+		t.Fatalf("expected Bob, got %v, %v", user, err)
+	}
+	if _, err := db.NewCreateUserQuery(stub).Eval(ctx, db.CreateUserParams{Name: "Carol", Email: fmt.Sprint(time.Now().UnixNano())}); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+
+	stub.AssertDone()
+
+	fakeT := &fakeT{}
+	stub = db.NewStubExecutor(fakeT, db.ExpectGetUserMatching(db.Eq(int64(1)), db.User{}, nil))
+	db.NewGetUserQuery(stub).Eval(ctx, 2)
+	if !fakeT.failed {
+		t.Error("expected mismatching argument to fail the test")
+	}
+
+	now := time.Now()
+	near := db.WithinDuration(now, time.Second)
+	for _, tt := range []struct {
+		arg  any
+		want bool
+	}{
+		{now.Add(500 * time.Millisecond), true},
+		{now.Add(-2 * time.Second), false},
+		{&now, true},
+		{pgtype.Timestamptz{Time: now, Valid: true}, true},
+		{"now", false},
+	} {
+		if got := near.Match(tt.arg); got != tt.want {
+			t.Errorf("WithinDuration.Match(%v) = %v, want %v", tt.arg, got, tt.want)
+		}
+	}
+}
+
+// TestInAnyOrder shows how to expect queries issued concurrently
+func TestInAnyOrder(t *testing.T) {
+	ctx := context.Background()
+
+	stub := db.NewStubExecutor(t,
+		db.ExpectCreateUser(db.CreateUserParams{Name: "Alice"}, db.User{ID: 1, Name: "Alice"}, nil),
+		db.InAnyOrder(
+			db.ExpectGetUser(1, db.User{ID: 1, Name: "Alice"}, nil),
+			db.ExpectGetUser(2, db.User{ID: 2, Name: "Bob"}, nil),
+			db.ExpectListUsers(nil, nil).Times(3),
+		),
+		db.ExpectDeleteUser(1, 1, nil),
+	)
+
+	if _, err := db.NewCreateUserQuery(stub).Eval(ctx, db.CreateUserParams{Name: "Alice"}); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for _, id := range []int64{2, 1} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if user, err := db.NewGetUserQuery(stub).Eval(ctx, id); err != nil || user.ID != id {
+				t.Errorf("GetUser(%d) = %v, %v", id, user, err)
+			}
+		}()
+	}
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := db.NewListUsersQuery(stub).Eval(ctx); err != nil {
+				t.Errorf("ListUsers failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, err := db.NewDeleteUserQuery(stub).Eval(ctx, 1); err != nil {
+		t.Fatalf("DeleteUser failed: %v", err)
+	}
+
+	stub.AssertDone()
+}
+
+// TestTimes shows how to expect the same query several times
+func TestTimes(t *testing.T) {
+	ctx := context.Background()
+
+	stub := db.NewStubExecutor(t,
+		db.ExpectGetUserMatching(db.Any(), db.User{Name: "Alice"}, nil).Times(2),
+		db.ExpectListUsers(nil, nil).AnyTimes(),
+		db.ExpectDeleteUser(1, 1, nil),
+		db.ExpectListUsers(nil, nil).AnyTimes(),
+	)
+
+	for id := range int64(2) {
+		if _, err := db.NewGetUserQuery(stub).Eval(ctx, id); err != nil {
+			t.Fatalf("GetUser failed: %v", err)
+		}
+	}
+	// ListUsers may be skipped entirely
+	if _, err := db.NewDeleteUserQuery(stub).Eval(ctx, 1); err != nil {
+		t.Fatalf("DeleteUser failed: %v", err)
+	}
+	for range 5 {
+		if _, err := db.NewListUsersQuery(stub).Eval(ctx); err != nil {
+			t.Fatalf("ListUsers failed: %v", err)
+		}
+	}
+	stub.AssertDone()
+
+	short := &fakeT{}
+	stub = db.NewStubExecutor(short, db.ExpectGetUser(1, db.User{}, nil).Times(2))
+	db.NewGetUserQuery(stub).Eval(ctx, 1)
+	stub.AssertDone()
+	if !short.failed {
+		t.Error("expected AssertDone to fail when a step ran fewer times than expected")
+	}
+
+	// Times(0) expects the query to never run
+	stub = db.NewStubExecutor(t, db.ExpectDeleteUser(1, 1, nil).Times(0))
+	stub.AssertDone()
+	never := &fakeT{}
+	stub = db.NewStubExecutor(never, db.ExpectDeleteUser(1, 1, nil).Times(0))
+	if _, err := db.NewDeleteUserQuery(stub).Eval(ctx, 1); err == nil || !never.failed {
+		t.Errorf("expected a step with Times(0) to fail the test when run, got %v", err)
+	}
+
+	negative := &fakeT{}
+	db.NewStubExecutor(negative, db.ExpectDeleteUser(1, 1, nil).Times(-1))
+	if !negative.failed {
+		t.Error("expected Times(-1) to fail the test")
+	}
+}
+
+// TestBatch shows how to stub the results of each query queued in a batch
+func TestBatch(t *testing.T) {
+	ctx := context.Background()
+	notFound := errors.New("not found")
+
+	stub := db.NewStubExecutor(t,
+		db.ExpectBatchGetUsers([]int64{1, 2}, []db.ResultOrErr[db.User]{
+			{Result: db.User{ID: 1, Name: "Alice"}},
+			{Err: notFound},
+		}),
+		db.ExpectBatchListUsersByName([]string{"Bob"}, []db.ResultOrErr[[]db.User]{
+			{Result: []db.User{{ID: 2, Name: "Bob"}, {ID: 3, Name: "Bob"}}},
+		}),
+		db.ExpectBatchDeleteUsers([]int64{1, 2}, []error{nil, notFound}),
+	)
+
+	results, err := db.NewBatchGetUsersQuery(stub).Eval(ctx, []int64{1, 2})
+	if err != nil {
+		t.Fatalf("BatchGetUsers failed: %v", err)
+	}
+	var users []db.User
+	var errs []error
+	results.QueryRow(func(i int, user db.User, err error) {
+		users = append(users, user)
+		errs = append(errs, err)
+	})
+	if users[0].Name != "Alice" || errs[0] != nil || !errors.Is(errs[1], notFound) {
+		t.Errorf("unexpected results: %v %v", users, errs)
+	}
+
+	many, err := db.NewBatchListUsersByNameQuery(stub).Eval(ctx, []string{"Bob"})
+	if err != nil {
+		t.Fatalf("BatchListUsersByName failed: %v", err)
+	}
+	many.Query(func(i int, users []db.User, err error) {
+		if err != nil || len(users) != 2 || users[1].ID != 3 {
+			t.Errorf("unexpected results: %v %v", users, err)
+		}
+	})
+
+	deleted, err := db.NewBatchDeleteUsersQuery(stub).Eval(ctx, []int64{1, 2})
+	if err != nil {
+		t.Fatalf("BatchDeleteUsers failed: %v", err)
+	}
+	errs = nil
+	deleted.Exec(func(i int, err error) {
+		errs = append(errs, err)
+	})
+	if len(errs) != 2 || errs[0] != nil || !errors.Is(errs[1], notFound) {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+
+	stub.AssertDone()
+}
+
+// TestCopyFrom shows how to stub a :copyfrom query
+func TestCopyFrom(t *testing.T) {
+	ctx := context.Background()
+	rows := []db.BulkInsertUsersParams{{Name: "Alice"}, {Name: "Bob"}}
+
+	stub := db.NewStubExecutor(t, db.ExpectBulkInsertUsers(rows, 2, nil))
+
+	n, err := db.NewBulkInsertUsersQuery(stub).Eval(ctx, rows)
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 rows copied, got %d, %v", n, err)
+	}
+	stub.AssertDone()
+
+	fakeT := &fakeT{}
+	stub = db.NewStubExecutor(fakeT, db.ExpectBulkInsertUsers(rows, 2, nil))
+	db.NewBulkInsertUsersQuery(stub).Eval(ctx, rows[:1])
+	if !fakeT.failed {
+		t.Error("expected different rows to fail the test")
+	}
+}
+
+// TestTransactions shows how to verify that queries run inside a transaction
+func TestTransactions(t *testing.T) {
+	ctx := context.Background()
+	errConflict := errors.New("conflict")
+
+	stub := db.NewStubExecutor(t,
+		db.ExpectBegin(),
+		db.ExpectCreateUser(db.CreateUserParams{Name: "Alice"}, db.User{ID: 1, Name: "Alice"}, nil),
+		db.ExpectCommit(),
+		db.ExpectBegin(),
+		db.ExpectDeleteUser(1, 0, errConflict),
+		db.ExpectRollback(),
+		db.ExpectBegin().WillReturnError(errConflict),
+	)
+
+	err := stub.WithTx(ctx, func(tx db.QueryExecutor) error {
+		_, err := db.NewCreateUserQuery(tx).Eval(ctx, db.CreateUserParams{Name: "Alice"})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = stub.WithTx(ctx, func(tx db.QueryExecutor) error {
+		_, err := db.NewDeleteUserQuery(tx).Eval(ctx, 1)
+		return err
+	})
+	if !errors.Is(err, errConflict) {
+		t.Fatalf("expected conflict, got %v", err)
+	}
+
+	err = stub.WithTx(ctx, func(tx db.QueryExecutor) error {
+		t.Error("callback must not run when begin fails")
+		return nil
+	})
+	if !errors.Is(err, errConflict) {
+		t.Fatalf("expected begin to fail, got %v", err)
+	}
+
+	stub.AssertDone()
+
+	// Queries outside of the expected transaction fail the test
+	queryT := &fakeT{}
+	stub = db.NewStubExecutor(queryT,
+		db.ExpectBegin(),
+		db.ExpectGetUser(1, db.User{}, nil),
+		db.ExpectCommit(),
+	)
+	db.NewGetUserQuery(stub).Eval(ctx, 1)
+	if !queryT.failed {
+		t.Error("expected query outside of the transaction to fail the test")
+	}
+
+	// A failing callback must roll back rather than commit
+	rollbackT := &fakeT{}
+	stub = db.NewStubExecutor(rollbackT, db.ExpectBegin(), db.ExpectCommit())
+	stub.WithTx(ctx, func(db.QueryExecutor) error { return errConflict })
+	if !rollbackT.failed {
+		t.Error("expected rollback instead of commit to fail the test")
+	}
+}
+
 // fakeT implements the testing interface to capture failures
 type fakeT struct {
 	failed bool
@@ -217,3 +521,518 @@ func (f *fakeT) Helper() {}
 func (f *fakeT) Errorf(format string, args ...any) {
 	f.failed = true
 }
+
+// TestReplay shows serving queries from fixture entries, as written by
+// RecordingExecutor.WriteFixture, that queries must match the recorded
+// arguments and that recorded errors keep their type
+func TestReplay(t *testing.T) {
+	ctx := context.Background()
+
+	columns := []db.FixtureColumn{
+		{Name: "id", OID: 20},
+		{Name: "name", OID: 25},
+		{Name: "email", OID: 25},
+	}
+	replay := db.NewReplayExecutor([]db.FixtureEntry{
+		{
+			Name:    "GetUser",
+			SQL:     db.NewGetUserQuery(nil).SQL(),
+			Args:    []byte(`[1]`),
+			Columns: columns,
+			Rows:    [][][]byte{{[]byte("1"), []byte("Alice"), []byte("alice@test.com")}},
+		},
+		{
+			Name:    "ListUsers",
+			SQL:     db.NewListUsersQuery(nil).SQL(),
+			Args:    []byte(`[]`),
+			Columns: columns,
+			Rows: [][][]byte{
+				{[]byte("1"), []byte("Alice"), []byte("alice@test.com")},
+				{[]byte("2"), []byte("Bob"), []byte("bob@test.com")},
+			},
+		},
+		{
+			Name:       "DeleteUser",
+			SQL:        db.NewDeleteUserQuery(nil).SQL(),
+			Args:       []byte(`[2]`),
+			CommandTag: "DELETE 1",
+		},
+		{
+			Name:    "GetUser",
+			SQL:     db.NewGetUserQuery(nil).SQL(),
+			Args:    []byte(`[2]`),
+			Columns: columns,
+		},
+	})
+
+	user, err := db.NewGetUserQuery(replay).Eval(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if user != (db.User{ID: 1, Name: "Alice", Email: "alice@test.com"}) {
+		t.Errorf("unexpected user %+v", user)
+	}
+
+	users, err := db.NewListUsersQuery(replay).Eval(ctx)
+	if err != nil {
+		t.Fatalf("ListUsers: %v", err)
+	}
+	if len(users) != 2 || users[1].Name != "Bob" {
+		t.Errorf("unexpected users %+v", users)
+	}
+
+	n, err := db.NewDeleteUserQuery(replay).Eval(ctx, 2)
+	if err != nil || n != 1 {
+		t.Fatalf("DeleteUser: %d, %v", n, err)
+	}
+
+	if _, err := db.NewGetUserQuery(replay).Eval(ctx, 2); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("expected pgx.ErrNoRows, got %v", err)
+	}
+
+	if _, err := db.NewGetUserQuery(replay).Eval(ctx, 3); err == nil {
+		t.Error("expected an error once the fixture is used up")
+	}
+	if replay.Remaining() != 0 {
+		t.Errorf("expected all entries to be replayed, %d left", replay.Remaining())
+	}
+
+	replay = db.NewReplayExecutor([]db.FixtureEntry{
+		{Name: "GetUser", SQL: db.NewGetUserQuery(nil).SQL(), Args: []byte(`[1]`)},
+	})
+	if _, err := db.NewGetUserQuery(replay).Eval(ctx, 2); err == nil || !strings.Contains(err.Error(), "differs from fixture") {
+		t.Errorf("expected a mismatch error, got %v", err)
+	}
+
+	data, err := json.Marshal([]db.FixtureEntry{
+		{
+			Name:    "DeleteUser",
+			SQL:     db.NewDeleteUserQuery(nil).SQL(),
+			Args:    []byte(`[1]`),
+			Error:   "ERROR: could not serialize access due to concurrent update (SQLSTATE 40001)",
+			PgError: &pgconn.PgError{Severity: "ERROR", Code: "40001", Message: "could not serialize access due to concurrent update"},
+		},
+		{
+			Name:    "GetUser",
+			SQL:     db.NewGetUserQuery(nil).SQL(),
+			Args:    []byte(`[1]`),
+			Error:   "context deadline exceeded",
+			ErrorIs: "context.DeadlineExceeded",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []db.FixtureEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatal(err)
+	}
+	replay = db.NewReplayExecutor(entries)
+
+	_, err = db.NewDeleteUserQuery(replay).Eval(ctx, 1)
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "40001" || !db.IsRetryable(err) {
+		t.Errorf("expected a retryable *pgconn.PgError, got %#v", err)
+	}
+	if err == nil || err.Error() != entries[0].Error {
+		t.Errorf("expected the recorded message, got %v", err)
+	}
+	if _, err := db.NewGetUserQuery(replay).Eval(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+// newFakeUsers returns a FakeExecutor implementing the user queries on top of
+// an in-memory users table
+func newFakeUsers(t *testing.T) *db.FakeExecutor {
+	fake := db.NewFakeExecutor(t)
+	users := db.TableOf[db.User](fake)
+	byID := func(id int64) func(db.User) bool {
+		return func(u db.User) bool { return u.ID == id }
+	}
+	fake.Handle(
+		db.HandleCreateUser(func(arg db.CreateUserParams) (db.User, error) {
+			user := db.User{ID: users.NextID(), Name: arg.Name, Email: arg.Email}
+			users.Insert(user)
+			return user, nil
+		}),
+		db.HandleGetUser(func(id int64) (db.User, error) {
+			user, ok := users.Find(byID(id))
+			if !ok {
+				return db.User{}, pgx.ErrNoRows
+			}
+			return user, nil
+		}),
+		db.HandleListUsers(func() ([]db.User, error) {
+			return users.Rows(), nil
+		}),
+		db.HandleDeleteUser(func(id int64) (int64, error) {
+			return users.Delete(byID(id)), nil
+		}),
+	)
+	return fake
+}
+
+// TestFakeExecutor shows running queries against in-memory tables, that WithTx
+// restores the tables when fn fails and that queries without a handler fail
+// the test
+func TestFakeExecutor(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeUsers(t)
+
+	alice, err := db.NewCreateUserQuery(fake).Eval(ctx, db.CreateUserParams{Name: "Alice", Email: "alice@test.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if _, err := db.NewCreateUserQuery(fake).Eval(ctx, db.CreateUserParams{Name: "Bob", Email: "bob@test.com"}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	user, err := db.NewGetUserQuery(fake).Eval(ctx, alice.ID)
+	if err != nil || user != alice {
+		t.Fatalf("GetUser: %+v, %v", user, err)
+	}
+
+	n, err := db.NewDeleteUserQuery(fake).Eval(ctx, alice.ID)
+	if err != nil || n != 1 {
+		t.Fatalf("DeleteUser: %d, %v", n, err)
+	}
+	if _, err := db.NewGetUserQuery(fake).Eval(ctx, alice.ID); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("expected pgx.ErrNoRows, got %v", err)
+	}
+
+	var names []string
+	for user, err := range db.NewListUsersQuery(fake).Iter(ctx) {
+		if err != nil {
+			t.Fatalf("ListUsers: %v", err)
+		}
+		names = append(names, user.Name)
+	}
+	if strings.Join(names, ",") != "Bob" {
+		t.Errorf("unexpected users %v", names)
+	}
+
+	users := db.TableOf[db.User](fake)
+	errAbort := errors.New("abort")
+	err = fake.WithTx(ctx, func(tx db.QueryExecutor) error {
+		if _, err := db.NewCreateUserQuery(tx).Eval(ctx, db.CreateUserParams{Name: "Carol"}); err != nil {
+			return err
+		}
+		users.Update(func(db.User) bool { return true }, func(u *db.User) { u.Email = "changed" })
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("expected errAbort, got %v", err)
+	}
+	rows := users.Rows()
+	if len(rows) != 1 || rows[0].Email != "bob@test.com" {
+		t.Errorf("expected the table to be restored, got %+v", rows)
+	}
+	if id := users.NextID(); id != 3 {
+		t.Errorf("expected the sequence to be restored, got %d", id)
+	}
+
+	err = fake.WithTx(ctx, func(tx db.QueryExecutor) error {
+		_, err := db.NewCreateUserQuery(tx).Eval(ctx, db.CreateUserParams{Name: "Dave"})
+		return err
+	})
+	if err != nil || users.Len() != 2 {
+		t.Errorf("expected the committed insert to be kept: %v, %d rows", err, users.Len())
+	}
+
+	ft := &fakeT{}
+	if _, err := db.NewGetUserQuery(db.NewFakeExecutor(ft)).Eval(ctx, 1); err == nil || !ft.failed {
+		t.Errorf("expected an unhandled query to fail the test, got %v", err)
+	}
+}
+
+// TestBatchBuilder shows that the queries queued in a Batch run one at a time
+// on the stub, so their usual expectations apply, and that a Batch stops at
+// the first failing query
+func TestBatchBuilder(t *testing.T) {
+	ctx := context.Background()
+
+	stub := db.NewStubExecutor(t,
+		db.ExpectGetUser(1, db.User{ID: 1, Name: "Alice"}, nil),
+		db.ExpectListUsers([]db.User{{ID: 1, Name: "Alice"}, {ID: 2, Name: "Bob"}}, nil),
+		db.ExpectDeleteUser(2, 1, nil),
+	)
+
+	get := db.NewGetUserQuery(stub).With(1)
+	list := db.NewListUsersQuery(stub)
+	del := db.NewDeleteUserQuery(stub).With(2)
+	if err := stub.Execute(ctx, db.NewBatch(get).Queue(list, del)); err != nil {
+		t.Fatalf("batch failed: %v", err)
+	}
+	if get.Result.Name != "Alice" || len(list.Results) != 2 || del.RowsAffected != 1 {
+		t.Errorf("unexpected results: %+v %+v %d", get.Result, list.Results, del.RowsAffected)
+	}
+	stub.AssertDone()
+
+	if err := stub.Execute(ctx, db.NewBatch(db.NewBulkInsertUsersQuery(stub))); err == nil {
+		t.Error("expected :copyfrom queries to be rejected")
+	}
+
+	fake := newFakeUsers(t)
+	create := db.NewCreateUserQuery(fake).With(db.CreateUserParams{Name: "Carol", Email: "carol@test.com"})
+	missing := db.NewGetUserQuery(fake).With(42)
+	skipped := db.NewCreateUserQuery(fake).With(db.CreateUserParams{Name: "Dave", Email: "dave@test.com"})
+	err := fake.Execute(ctx, db.NewBatch(create, missing, skipped))
+	if !errors.Is(err, pgx.ErrNoRows) || !strings.HasPrefix(err.Error(), "GetUser: ") {
+		t.Errorf("expected GetUser to fail with pgx.ErrNoRows, got %v", err)
+	}
+	if create.Result.Name != "Carol" {
+		t.Errorf("expected Carol to be created, got %+v", create.Result)
+	}
+	if db.TableOf[db.User](fake).Len() != 1 {
+		t.Errorf("expected the queries after GetUser not to run")
+	}
+}
+
+// concurrencyExecutor records how many queries run at the same time. Queries
+// take delay to finish unless their context is canceled first, GetUser
+// queries fail with err.
+type concurrencyExecutor struct {
+	db.QueryExecutor
+	delay time.Duration
+	err   error
+
+	mu      sync.Mutex
+	running int
+	max     int
+}
+
+func (e *concurrencyExecutor) Execute(ctx context.Context, q db.Query) error {
+	e.mu.Lock()
+	e.running++
+	e.max = max(e.max, e.running)
+	e.mu.Unlock()
+	defer func() {
+		e.mu.Lock()
+		e.running--
+		e.mu.Unlock()
+	}()
+
+	if q.QueryName() == "GetUser" {
+		return e.err
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(e.delay):
+		return nil
+	}
+}
+
+// TestExecuteAll shows that ExecuteAll runs read queries concurrently up to
+// its limit, and that the first failing query cancels the others
+func TestExecuteAll(t *testing.T) {
+	ctx := context.Background()
+
+	ex := &concurrencyExecutor{delay: 10 * time.Millisecond}
+	var queries []db.Query
+	for range 10 {
+		queries = append(queries, db.NewListUsersQuery(ex))
+	}
+	if err := db.ExecuteAll(ctx, ex, 3, queries...); err != nil {
+		t.Fatalf("ExecuteAll failed: %v", err)
+	}
+	if ex.max < 2 || ex.max > 3 {
+		t.Errorf("expected 2 to 3 concurrent queries, got %d", ex.max)
+	}
+
+	errFail := errors.New("fail")
+	ex = &concurrencyExecutor{delay: time.Minute, err: errFail}
+	start := time.Now()
+	err := db.ExecuteAll(ctx, ex, 0,
+		db.NewListUsersQuery(ex),
+		db.NewListUsersQuery(ex),
+		db.NewGetUserQuery(ex),
+	)
+	if !errors.Is(err, errFail) || !strings.HasPrefix(err.Error(), "GetUser: ") {
+		t.Errorf("expected the GetUser error, got %v", err)
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Error("expected the other queries to be canceled")
+	}
+
+	// write queries are rejected before anything runs
+	ex = &concurrencyExecutor{}
+	err = db.ExecuteAll(ctx, ex, 0, db.NewListUsersQuery(ex), db.NewDeleteUserQuery(ex))
+	if err == nil || !strings.HasPrefix(err.Error(), "DeleteUser :execrows queries cannot be run") {
+		t.Errorf("expected DeleteUser to be rejected, got %v", err)
+	}
+	if ex.max != 0 {
+		t.Errorf("expected no query to run, got %d", ex.max)
+	}
+
+	// CreateUser is an INSERT ... RETURNING, it must run on the primary
+	err = db.ExecuteAll(ctx, ex, 0, db.NewListUsersQuery(ex), db.NewCreateUserQuery(ex))
+	if err == nil || !strings.HasPrefix(err.Error(), "CreateUser must run on the primary") {
+		t.Errorf("expected CreateUser to be rejected, got %v", err)
+	}
+	if ex.max != 0 {
+		t.Errorf("expected no query to run, got %d", ex.max)
+	}
+}
+
+// batchDB is a DBTX that records the SQL and context of the batches sent to
+// it and reports one affected row for every query
+type batchDB struct {
+	db.DBTX
+	sqls     []string
+	deadline bool
+}
+
+func (b *batchDB) SendBatch(ctx context.Context, batch *pgx.Batch) pgx.BatchResults {
+	for _, q := range batch.QueuedQueries {
+		b.sqls = append(b.sqls, q.SQL)
+	}
+	_, b.deadline = ctx.Deadline()
+	return execBatchResults{}
+}
+
+type execBatchResults struct {
+	pgx.BatchResults
+}
+
+func (execBatchResults) Exec() (pgconn.CommandTag, error) {
+	return pgconn.NewCommandTag("DELETE 1"), nil
+}
+
+func (execBatchResults) Close() error {
+	return nil
+}
+
+// TestBatchSQLComments shows that queries sent in a Batch by Executor get the
+// sqlcommenter tags and the timeout of the Executor path
+func TestBatchSQLComments(t *testing.T) {
+	conn := &batchDB{}
+	executor := db.NewExecutor(conn, db.WithSQLComments(map[string]string{"application": "users-api"}))
+	ctx := db.ContextWithSQLCommentTags(context.Background(), map[string]string{"route": "/users"})
+
+	del := db.NewDeleteUserQuery(executor).With(1)
+	if err := executor.Execute(ctx, db.NewBatch(del, db.NewDeleteUserQuery(executor).With(2))); err != nil {
+		t.Fatalf("batch failed: %v", err)
+	}
+	if del.RowsAffected != 1 {
+		t.Errorf("expected 1 row affected, got %d", del.RowsAffected)
+	}
+	if len(conn.sqls) != 2 {
+		t.Fatalf("expected 2 queued queries, got %d", len(conn.sqls))
+	}
+	for _, sql := range conn.sqls {
+		for _, tag := range []string{"application='users-api'", "query='DeleteUser'", "route='%2Fusers'"} {
+			if !strings.Contains(sql, tag) {
+				t.Errorf("expected %s in %q", tag, sql)
+			}
+		}
+	}
+	if !conn.deadline {
+		t.Error("expected the batch to run with the DeleteUser timeout")
+	}
+
+	conn.sqls = nil
+	if err := executor.Execute(ctx, db.NewBatch(db.NewBulkInsertUsersQuery(executor))); err == nil || len(conn.sqls) != 0 {
+		t.Errorf("expected :copyfrom queries to be rejected before sending the batch, got %v", err)
+	}
+}
+
+// TestQueryMetadata shows the metadata exposed to executors and middleware
+func TestQueryMetadata(t *testing.T) {
+	var q db.Query = db.NewGetUserQuery(nil)
+	if q.QueryName() != "GetUser" {
+		t.Errorf("expected GetUser, got %s", q.QueryName())
+	}
+	if q.QueryCmd() != ":one" {
+		t.Errorf("expected :one, got %s", q.QueryCmd())
+	}
+	if q.SourceFile() != "query.sql" {
+		t.Errorf("expected query.sql, got %s", q.SourceFile())
+	}
+
+	q = db.NewListUsersQuery(nil)
+	if q.QueryCmd() != ":many" {
+		t.Errorf("expected :many, got %s", q.QueryCmd())
+	}
+}
+
+// TestIter shows that stubbed results are streamed through Iter
+func TestIter(t *testing.T) {
+	ctx := context.Background()
+
+	stub := db.NewStubExecutor(t,
+		db.ExpectListUsers([]db.User{
+			{ID: 1, Name: "Alice"},
+			{ID: 2, Name: "Bob"},
+			{ID: 3, Name: "Carol"},
+		}, nil),
+		db.ExpectListUsers(nil, errors.New("connection lost")),
+	)
+
+	var names []string
+	for user, err := range db.NewListUsersQuery(stub).Iter(ctx) {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		names = append(names, user.Name)
+		if len(names) == 2 {
+			break
+		}
+	}
+	if len(names) != 2 || names[1] != "Bob" {
+		t.Errorf("expected [Alice Bob], got %v", names)
+	}
+
+	for _, err := range db.NewListUsersQuery(stub).Iter(ctx) {
+		if err == nil || err.Error() != "connection lost" {
+			t.Errorf("expected connection lost error, got %v", err)
+		}
+	}
+
+	stub.AssertDone()
+}
+
+// TestWithTxOptions shows how to assert on the options a transaction was started with
+func TestWithTxOptions(t *testing.T) {
+	ctx := context.Background()
+
+	stub := db.NewStubExecutor(t,
+		db.ExpectGetUser(1, db.User{ID: 1, Name: "Alice"}, nil),
+	)
+
+	opts := db.TxOptions{IsoLevel: pgx.Serializable, AccessMode: pgx.ReadOnly}
+	err := stub.WithTxOptions(ctx, opts, func(ex db.QueryExecutor) error {
+		_, err := db.NewGetUserQuery(ex).Eval(ctx, 1)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := stub.TxOptions()
+	if len(got) != 1 || got[0] != opts {
+		t.Errorf("expected %+v, got %+v", opts, got)
+	}
+
+	stub.AssertDone()
+}
+
+// TestIsRetryable shows which errors make WithTx retry under a RetryPolicy
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&pgconn.PgError{Code: "40001"}, true},
+		{fmt.Errorf("commit: %w", &pgconn.PgError{Code: "40P01"}), true},
+		{&pgconn.PgError{Code: "23505"}, false},
+		{errors.New("connection reset"), false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := db.IsRetryable(tt.err); got != tt.want {
+			t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
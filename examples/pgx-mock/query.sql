@@ -8,4 +8,17 @@ SELECT * FROM users ORDER BY id;
 INSERT INTO users (name, email) VALUES ($1, $2) RETURNING *;
 
 -- name: DeleteUser :execrows
+-- @timeout 2s
 DELETE FROM users WHERE id = $1;
+
+-- name: BatchGetUsers :batchone
+SELECT * FROM users WHERE id = $1;
+
+-- name: BatchListUsersByName :batchmany
+SELECT * FROM users WHERE name = $1;
+
+-- name: BatchDeleteUsers :batchexec
+DELETE FROM users WHERE id = $1;
+
+-- name: BulkInsertUsers :copyfrom
+INSERT INTO users (name, email) VALUES ($1, $2);
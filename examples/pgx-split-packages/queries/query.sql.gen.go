@@ -7,6 +7,7 @@ package queries
 
 import (
 	"context"
+	"iter"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -27,6 +28,18 @@ func (q *CountAccountsQuery) SQL() string {
 	return countAccounts
 }
 
+func (q *CountAccountsQuery) QueryName() string {
+	return "CountAccounts"
+}
+
+func (q *CountAccountsQuery) QueryCmd() string {
+	return ":one"
+}
+
+func (q *CountAccountsQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *CountAccountsQuery) Args() []any {
 	return nil
 }
@@ -73,6 +86,18 @@ func (q *CountPostsQuery) SQL() string {
 	return countPosts
 }
 
+func (q *CountPostsQuery) QueryName() string {
+	return "CountPosts"
+}
+
+func (q *CountPostsQuery) QueryCmd() string {
+	return ":one"
+}
+
+func (q *CountPostsQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *CountPostsQuery) Args() []any {
 	return nil
 }
@@ -129,6 +154,23 @@ func (q *CreateAccountQuery) SQL() string {
 	return createAccount
 }
 
+func (q *CreateAccountQuery) QueryName() string {
+	return "CreateAccount"
+}
+
+func (q *CreateAccountQuery) QueryCmd() string {
+	return ":one"
+}
+
+func (q *CreateAccountQuery) SourceFile() string {
+	return "query.sql"
+}
+
+// UsesPrimary makes RoutingExecutor run the query on the primary
+func (q *CreateAccountQuery) UsesPrimary() bool {
+	return true
+}
+
 func (q *CreateAccountQuery) Args() []any {
 	return []any{q.arg.Username, q.arg.Email, q.arg.Role, q.arg.Status}
 }
@@ -157,6 +199,12 @@ func (q *CreateAccountQuery) Eval(ctx context.Context, arg CreateAccountParams)
 	return q.Result, nil
 }
 
+// With sets the arguments of the query without executing it, e.g. to queue it in a Batch
+func (q *CreateAccountQuery) With(arg CreateAccountParams) *CreateAccountQuery {
+	q.arg = arg
+	return q
+}
+
 func NewCreateAccountQuery(ex db.QueryExecutor) *CreateAccountQuery {
 	return &CreateAccountQuery{ex: ex}
 }
@@ -171,6 +219,17 @@ func ExpectCreateAccount(arg CreateAccountParams, result models.Account, err err
 	}
 }
 
+func ExpectCreateAccountMatching(username, email, role, status db.ArgMatcher, result models.Account, err error) db.Step {
+	return db.Step{
+		SQL:  createAccount,
+		Args: []any{username, email, role, status},
+		Apply: func(q db.Query) error {
+			q.(*CreateAccountQuery).SetResult(result)
+			return err
+		},
+	}
+}
+
 const createPost = `-- name: CreatePost :one
 INSERT INTO posts (account_id, title, content, published)
 VALUES ($1, $2, $3, $4)
@@ -194,6 +253,23 @@ func (q *CreatePostQuery) SQL() string {
 	return createPost
 }
 
+func (q *CreatePostQuery) QueryName() string {
+	return "CreatePost"
+}
+
+func (q *CreatePostQuery) QueryCmd() string {
+	return ":one"
+}
+
+func (q *CreatePostQuery) SourceFile() string {
+	return "query.sql"
+}
+
+// UsesPrimary makes RoutingExecutor run the query on the primary
+func (q *CreatePostQuery) UsesPrimary() bool {
+	return true
+}
+
 func (q *CreatePostQuery) Args() []any {
 	return []any{q.arg.AccountID, q.arg.Title, q.arg.Content, q.arg.Published}
 }
@@ -221,6 +297,12 @@ func (q *CreatePostQuery) Eval(ctx context.Context, arg CreatePostParams) (model
 	return q.Result, nil
 }
 
+// With sets the arguments of the query without executing it, e.g. to queue it in a Batch
+func (q *CreatePostQuery) With(arg CreatePostParams) *CreatePostQuery {
+	q.arg = arg
+	return q
+}
+
 func NewCreatePostQuery(ex db.QueryExecutor) *CreatePostQuery {
 	return &CreatePostQuery{ex: ex}
 }
@@ -235,6 +317,17 @@ func ExpectCreatePost(arg CreatePostParams, result models.Post, err error) db.St
 	}
 }
 
+func ExpectCreatePostMatching(accountID, title, content, published db.ArgMatcher, result models.Post, err error) db.Step {
+	return db.Step{
+		SQL:  createPost,
+		Args: []any{accountID, title, content, published},
+		Apply: func(q db.Query) error {
+			q.(*CreatePostQuery).SetResult(result)
+			return err
+		},
+	}
+}
+
 const deleteAccount = `-- name: DeleteAccount :exec
 DELETE FROM accounts
 WHERE id = $1
@@ -250,6 +343,18 @@ func (q *DeleteAccountQuery) SQL() string {
 	return deleteAccount
 }
 
+func (q *DeleteAccountQuery) QueryName() string {
+	return "DeleteAccount"
+}
+
+func (q *DeleteAccountQuery) QueryCmd() string {
+	return ":exec"
+}
+
+func (q *DeleteAccountQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *DeleteAccountQuery) Args() []any {
 	return []any{q.id}
 }
@@ -262,6 +367,12 @@ func (q *DeleteAccountQuery) Eval(ctx context.Context, id int64) error {
 	return q.ex.Execute(ctx, q)
 }
 
+// With sets the arguments of the query without executing it, e.g. to queue it in a Batch
+func (q *DeleteAccountQuery) With(id int64) *DeleteAccountQuery {
+	q.id = id
+	return q
+}
+
 func NewDeleteAccountQuery(ex db.QueryExecutor) *DeleteAccountQuery {
 	return &DeleteAccountQuery{ex: ex}
 }
@@ -275,6 +386,16 @@ func ExpectDeleteAccount(id int64, err error) db.Step {
 	}
 }
 
+func ExpectDeleteAccountMatching(id db.ArgMatcher, err error) db.Step {
+	return db.Step{
+		SQL:  deleteAccount,
+		Args: []any{id},
+		Apply: func(q db.Query) error {
+			return err
+		},
+	}
+}
+
 const getAccount = `-- name: GetAccount :one
 SELECT id, username, email, role, status, created_at, updated_at FROM accounts
 WHERE id = $1
@@ -290,6 +411,18 @@ func (q *GetAccountQuery) SQL() string {
 	return getAccount
 }
 
+func (q *GetAccountQuery) QueryName() string {
+	return "GetAccount"
+}
+
+func (q *GetAccountQuery) QueryCmd() string {
+	return ":one"
+}
+
+func (q *GetAccountQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *GetAccountQuery) Args() []any {
 	return []any{q.id}
 }
@@ -318,6 +451,12 @@ func (q *GetAccountQuery) Eval(ctx context.Context, id int64) (models.Account, e
 	return q.Result, nil
 }
 
+// With sets the arguments of the query without executing it, e.g. to queue it in a Batch
+func (q *GetAccountQuery) With(id int64) *GetAccountQuery {
+	q.id = id
+	return q
+}
+
 func NewGetAccountQuery(ex db.QueryExecutor) *GetAccountQuery {
 	return &GetAccountQuery{ex: ex}
 }
@@ -332,6 +471,17 @@ func ExpectGetAccount(id int64, result models.Account, err error) db.Step {
 	}
 }
 
+func ExpectGetAccountMatching(id db.ArgMatcher, result models.Account, err error) db.Step {
+	return db.Step{
+		SQL:  getAccount,
+		Args: []any{id},
+		Apply: func(q db.Query) error {
+			q.(*GetAccountQuery).SetResult(result)
+			return err
+		},
+	}
+}
+
 const getAccountByUsername = `-- name: GetAccountByUsername :one
 SELECT id, username, email, role, status, created_at, updated_at FROM accounts
 WHERE username = $1
@@ -347,6 +497,18 @@ func (q *GetAccountByUsernameQuery) SQL() string {
 	return getAccountByUsername
 }
 
+func (q *GetAccountByUsernameQuery) QueryName() string {
+	return "GetAccountByUsername"
+}
+
+func (q *GetAccountByUsernameQuery) QueryCmd() string {
+	return ":one"
+}
+
+func (q *GetAccountByUsernameQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *GetAccountByUsernameQuery) Args() []any {
 	return []any{q.username}
 }
@@ -375,6 +537,12 @@ func (q *GetAccountByUsernameQuery) Eval(ctx context.Context, username string) (
 	return q.Result, nil
 }
 
+// With sets the arguments of the query without executing it, e.g. to queue it in a Batch
+func (q *GetAccountByUsernameQuery) With(username string) *GetAccountByUsernameQuery {
+	q.username = username
+	return q
+}
+
 func NewGetAccountByUsernameQuery(ex db.QueryExecutor) *GetAccountByUsernameQuery {
 	return &GetAccountByUsernameQuery{ex: ex}
 }
@@ -389,6 +557,17 @@ func ExpectGetAccountByUsername(username string, result models.Account, err erro
 	}
 }
 
+func ExpectGetAccountByUsernameMatching(username db.ArgMatcher, result models.Account, err error) db.Step {
+	return db.Step{
+		SQL:  getAccountByUsername,
+		Args: []any{username},
+		Apply: func(q db.Query) error {
+			q.(*GetAccountByUsernameQuery).SetResult(result)
+			return err
+		},
+	}
+}
+
 const getPost = `-- name: GetPost :one
 SELECT p.id, p.account_id, p.title, p.content, p.published, p.created_at, a.username, a.role
 FROM posts p
@@ -417,6 +596,18 @@ func (q *GetPostQuery) SQL() string {
 	return getPost
 }
 
+func (q *GetPostQuery) QueryName() string {
+	return "GetPost"
+}
+
+func (q *GetPostQuery) QueryCmd() string {
+	return ":one"
+}
+
+func (q *GetPostQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *GetPostQuery) Args() []any {
 	return []any{q.id}
 }
@@ -446,6 +637,12 @@ func (q *GetPostQuery) Eval(ctx context.Context, id int64) (GetPostRow, error) {
 	return q.Result, nil
 }
 
+// With sets the arguments of the query without executing it, e.g. to queue it in a Batch
+func (q *GetPostQuery) With(id int64) *GetPostQuery {
+	q.id = id
+	return q
+}
+
 func NewGetPostQuery(ex db.QueryExecutor) *GetPostQuery {
 	return &GetPostQuery{ex: ex}
 }
@@ -460,6 +657,17 @@ func ExpectGetPost(id int64, result GetPostRow, err error) db.Step {
 	}
 }
 
+func ExpectGetPostMatching(id db.ArgMatcher, result GetPostRow, err error) db.Step {
+	return db.Step{
+		SQL:  getPost,
+		Args: []any{id},
+		Apply: func(q db.Query) error {
+			q.(*GetPostQuery).SetResult(result)
+			return err
+		},
+	}
+}
+
 const listAccounts = `-- name: ListAccounts :many
 SELECT id, username, email, role, status, created_at, updated_at FROM accounts
 ORDER BY created_at DESC
@@ -477,6 +685,18 @@ func (q *ListAccountsQuery) SQL() string {
 	return listAccounts
 }
 
+func (q *ListAccountsQuery) QueryName() string {
+	return "ListAccounts"
+}
+
+func (q *ListAccountsQuery) QueryCmd() string {
+	return ":many"
+}
+
+func (q *ListAccountsQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *ListAccountsQuery) Args() []any {
 	return []any{q.limit, q.offset}
 }
@@ -512,6 +732,60 @@ func (q *ListAccountsQuery) Eval(ctx context.Context, limit int32, offset int32)
 	return q.Results, nil
 }
 
+// listAccountsStream hands ListAccounts rows to an Iter loop one at a time.
+type listAccountsStream struct {
+	*ListAccountsQuery
+	yield   func(models.Account, error) bool
+	stopped bool
+}
+
+func (s *listAccountsStream) ScanNext(row pgx.Row) (bool, error) {
+	var i models.Account
+	if err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.Role,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	); err != nil {
+		return false, err
+	}
+	s.stopped = !s.yield(i, nil)
+	return !s.stopped, nil
+}
+
+// SetResults yields results provided by a StubExecutor step.
+func (s *listAccountsStream) SetResults(results []models.Account) {
+	for _, r := range results {
+		if s.stopped = !s.yield(r, nil); s.stopped {
+			return
+		}
+	}
+}
+
+// Iter executes the query and yields rows as they are scanned. The rows are
+// closed when the loop finishes or breaks early.
+func (q *ListAccountsQuery) Iter(ctx context.Context, limit int32, offset int32) iter.Seq2[models.Account, error] {
+	return func(yield func(models.Account, error) bool) {
+		q.limit = limit
+		q.offset = offset
+		s := &listAccountsStream{ListAccountsQuery: q, yield: yield}
+		if err := q.ex.Execute(ctx, s); err != nil && !s.stopped {
+			var zero models.Account
+			yield(zero, err)
+		}
+	}
+}
+
+// With sets the arguments of the query without executing it, e.g. to queue it in a Batch
+func (q *ListAccountsQuery) With(limit int32, offset int32) *ListAccountsQuery {
+	q.limit = limit
+	q.offset = offset
+	return q
+}
+
 func NewListAccountsQuery(ex db.QueryExecutor) *ListAccountsQuery {
 	return &ListAccountsQuery{ex: ex}
 }
@@ -520,7 +794,18 @@ func ExpectListAccounts(limit int32, offset int32, results []models.Account, err
 		SQL:  listAccounts,
 		Args: []any{limit, offset},
 		Apply: func(q db.Query) error {
-			q.(*ListAccountsQuery).SetResults(results)
+			q.(interface{ SetResults([]models.Account) }).SetResults(results)
+			return err
+		},
+	}
+}
+
+func ExpectListAccountsMatching(limit, offset db.ArgMatcher, results []models.Account, err error) db.Step {
+	return db.Step{
+		SQL:  listAccounts,
+		Args: []any{limit, offset},
+		Apply: func(q db.Query) error {
+			q.(interface{ SetResults([]models.Account) }).SetResults(results)
 			return err
 		},
 	}
@@ -542,6 +827,18 @@ func (q *ListAccountsByRoleQuery) SQL() string {
 	return listAccountsByRole
 }
 
+func (q *ListAccountsByRoleQuery) QueryName() string {
+	return "ListAccountsByRole"
+}
+
+func (q *ListAccountsByRoleQuery) QueryCmd() string {
+	return ":many"
+}
+
+func (q *ListAccountsByRoleQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *ListAccountsByRoleQuery) Args() []any {
 	return []any{q.role}
 }
@@ -576,6 +873,58 @@ func (q *ListAccountsByRoleQuery) Eval(ctx context.Context, role models.UserRole
 	return q.Results, nil
 }
 
+// listAccountsByRoleStream hands ListAccountsByRole rows to an Iter loop one at a time.
+type listAccountsByRoleStream struct {
+	*ListAccountsByRoleQuery
+	yield   func(models.Account, error) bool
+	stopped bool
+}
+
+func (s *listAccountsByRoleStream) ScanNext(row pgx.Row) (bool, error) {
+	var i models.Account
+	if err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.Role,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	); err != nil {
+		return false, err
+	}
+	s.stopped = !s.yield(i, nil)
+	return !s.stopped, nil
+}
+
+// SetResults yields results provided by a StubExecutor step.
+func (s *listAccountsByRoleStream) SetResults(results []models.Account) {
+	for _, r := range results {
+		if s.stopped = !s.yield(r, nil); s.stopped {
+			return
+		}
+	}
+}
+
+// Iter executes the query and yields rows as they are scanned. The rows are
+// closed when the loop finishes or breaks early.
+func (q *ListAccountsByRoleQuery) Iter(ctx context.Context, role models.UserRole) iter.Seq2[models.Account, error] {
+	return func(yield func(models.Account, error) bool) {
+		q.role = role
+		s := &listAccountsByRoleStream{ListAccountsByRoleQuery: q, yield: yield}
+		if err := q.ex.Execute(ctx, s); err != nil && !s.stopped {
+			var zero models.Account
+			yield(zero, err)
+		}
+	}
+}
+
+// With sets the arguments of the query without executing it, e.g. to queue it in a Batch
+func (q *ListAccountsByRoleQuery) With(role models.UserRole) *ListAccountsByRoleQuery {
+	q.role = role
+	return q
+}
+
 func NewListAccountsByRoleQuery(ex db.QueryExecutor) *ListAccountsByRoleQuery {
 	return &ListAccountsByRoleQuery{ex: ex}
 }
@@ -584,7 +933,18 @@ func ExpectListAccountsByRole(role models.UserRole, results []models.Account, er
 		SQL:  listAccountsByRole,
 		Args: []any{role},
 		Apply: func(q db.Query) error {
-			q.(*ListAccountsByRoleQuery).SetResults(results)
+			q.(interface{ SetResults([]models.Account) }).SetResults(results)
+			return err
+		},
+	}
+}
+
+func ExpectListAccountsByRoleMatching(role db.ArgMatcher, results []models.Account, err error) db.Step {
+	return db.Step{
+		SQL:  listAccountsByRole,
+		Args: []any{role},
+		Apply: func(q db.Query) error {
+			q.(interface{ SetResults([]models.Account) }).SetResults(results)
 			return err
 		},
 	}
@@ -606,6 +966,18 @@ func (q *ListPostsByAccountQuery) SQL() string {
 	return listPostsByAccount
 }
 
+func (q *ListPostsByAccountQuery) QueryName() string {
+	return "ListPostsByAccount"
+}
+
+func (q *ListPostsByAccountQuery) QueryCmd() string {
+	return ":many"
+}
+
+func (q *ListPostsByAccountQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *ListPostsByAccountQuery) Args() []any {
 	return []any{q.accountID}
 }
@@ -639,6 +1011,57 @@ func (q *ListPostsByAccountQuery) Eval(ctx context.Context, accountID int64) ([]
 	return q.Results, nil
 }
 
+// listPostsByAccountStream hands ListPostsByAccount rows to an Iter loop one at a time.
+type listPostsByAccountStream struct {
+	*ListPostsByAccountQuery
+	yield   func(models.Post, error) bool
+	stopped bool
+}
+
+func (s *listPostsByAccountStream) ScanNext(row pgx.Row) (bool, error) {
+	var i models.Post
+	if err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Title,
+		&i.Content,
+		&i.Published,
+		&i.CreatedAt,
+	); err != nil {
+		return false, err
+	}
+	s.stopped = !s.yield(i, nil)
+	return !s.stopped, nil
+}
+
+// SetResults yields results provided by a StubExecutor step.
+func (s *listPostsByAccountStream) SetResults(results []models.Post) {
+	for _, r := range results {
+		if s.stopped = !s.yield(r, nil); s.stopped {
+			return
+		}
+	}
+}
+
+// Iter executes the query and yields rows as they are scanned. The rows are
+// closed when the loop finishes or breaks early.
+func (q *ListPostsByAccountQuery) Iter(ctx context.Context, accountID int64) iter.Seq2[models.Post, error] {
+	return func(yield func(models.Post, error) bool) {
+		q.accountID = accountID
+		s := &listPostsByAccountStream{ListPostsByAccountQuery: q, yield: yield}
+		if err := q.ex.Execute(ctx, s); err != nil && !s.stopped {
+			var zero models.Post
+			yield(zero, err)
+		}
+	}
+}
+
+// With sets the arguments of the query without executing it, e.g. to queue it in a Batch
+func (q *ListPostsByAccountQuery) With(accountID int64) *ListPostsByAccountQuery {
+	q.accountID = accountID
+	return q
+}
+
 func NewListPostsByAccountQuery(ex db.QueryExecutor) *ListPostsByAccountQuery {
 	return &ListPostsByAccountQuery{ex: ex}
 }
@@ -647,7 +1070,18 @@ func ExpectListPostsByAccount(accountID int64, results []models.Post, err error)
 		SQL:  listPostsByAccount,
 		Args: []any{accountID},
 		Apply: func(q db.Query) error {
-			q.(*ListPostsByAccountQuery).SetResults(results)
+			q.(interface{ SetResults([]models.Post) }).SetResults(results)
+			return err
+		},
+	}
+}
+
+func ExpectListPostsByAccountMatching(accountID db.ArgMatcher, results []models.Post, err error) db.Step {
+	return db.Step{
+		SQL:  listPostsByAccount,
+		Args: []any{accountID},
+		Apply: func(q db.Query) error {
+			q.(interface{ SetResults([]models.Post) }).SetResults(results)
 			return err
 		},
 	}
@@ -669,6 +1103,18 @@ func (q *PublishPostQuery) SQL() string {
 	return publishPost
 }
 
+func (q *PublishPostQuery) QueryName() string {
+	return "PublishPost"
+}
+
+func (q *PublishPostQuery) QueryCmd() string {
+	return ":execrows"
+}
+
+func (q *PublishPostQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *PublishPostQuery) Args() []any {
 	return []any{q.id}
 }
@@ -685,6 +1131,12 @@ func (q *PublishPostQuery) Eval(ctx context.Context, id int64) (int64, error) {
 	return q.RowsAffected, nil
 }
 
+// With sets the arguments of the query without executing it, e.g. to queue it in a Batch
+func (q *PublishPostQuery) With(id int64) *PublishPostQuery {
+	q.id = id
+	return q
+}
+
 func NewPublishPostQuery(ex db.QueryExecutor) *PublishPostQuery {
 	return &PublishPostQuery{ex: ex}
 }
@@ -699,6 +1151,17 @@ func ExpectPublishPost(id int64, rowsAffected int64, err error) db.Step {
 	}
 }
 
+func ExpectPublishPostMatching(id db.ArgMatcher, rowsAffected int64, err error) db.Step {
+	return db.Step{
+		SQL:  publishPost,
+		Args: []any{id},
+		Apply: func(q db.Query) error {
+			q.(*PublishPostQuery).SetRowsAffected(rowsAffected)
+			return err
+		},
+	}
+}
+
 const updateAccountStatus = `-- name: UpdateAccountStatus :execrows
 UPDATE accounts
 SET status = $2, updated_at = NOW()
@@ -716,6 +1179,18 @@ func (q *UpdateAccountStatusQuery) SQL() string {
 	return updateAccountStatus
 }
 
+func (q *UpdateAccountStatusQuery) QueryName() string {
+	return "UpdateAccountStatus"
+}
+
+func (q *UpdateAccountStatusQuery) QueryCmd() string {
+	return ":execrows"
+}
+
+func (q *UpdateAccountStatusQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *UpdateAccountStatusQuery) Args() []any {
 	return []any{q.iD, q.status}
 }
@@ -733,6 +1208,13 @@ func (q *UpdateAccountStatusQuery) Eval(ctx context.Context, iD int64, status mo
 	return q.RowsAffected, nil
 }
 
+// With sets the arguments of the query without executing it, e.g. to queue it in a Batch
+func (q *UpdateAccountStatusQuery) With(iD int64, status models.AccountStatus) *UpdateAccountStatusQuery {
+	q.iD = iD
+	q.status = status
+	return q
+}
+
 func NewUpdateAccountStatusQuery(ex db.QueryExecutor) *UpdateAccountStatusQuery {
 	return &UpdateAccountStatusQuery{ex: ex}
 }
@@ -746,3 +1228,14 @@ func ExpectUpdateAccountStatus(iD int64, status models.AccountStatus, rowsAffect
 		},
 	}
 }
+
+func ExpectUpdateAccountStatusMatching(iD, status db.ArgMatcher, rowsAffected int64, err error) db.Step {
+	return db.Step{
+		SQL:  updateAccountStatus,
+		Args: []any{iD, status},
+		Apply: func(q db.Query) error {
+			q.(*UpdateAccountStatusQuery).SetRowsAffected(rowsAffected)
+			return err
+		},
+	}
+}
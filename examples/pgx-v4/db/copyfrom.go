@@ -55,6 +55,18 @@ func (q *bulkInsertUsersQuery) SQL() string {
 	return ""
 }
 
+func (q *bulkInsertUsersQuery) QueryName() string {
+	return "BulkInsertUsers"
+}
+
+func (q *bulkInsertUsersQuery) QueryCmd() string {
+	return ":copyfrom"
+}
+
+func (q *bulkInsertUsersQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *bulkInsertUsersQuery) Args() []any {
 	return nil
 }
@@ -79,6 +91,21 @@ func NewBulkInsertUsersQuery(ex QueryExecutor) *bulkInsertUsersQuery {
 	return &bulkInsertUsersQuery{ex: ex}
 }
 
+func (q *bulkInsertUsersQuery) stubArgs() []any {
+	return []any{q.rows}
+}
+
+func ExpectBulkInsertUsers(arg []BulkInsertUsersParams, copied int64, err error) Step {
+	return Step{
+		SQL:  "",
+		Args: []any{arg},
+		Apply: func(q Query) error {
+			q.(*bulkInsertUsersQuery).SetRowsCopied(copied)
+			return err
+		},
+	}
+}
+
 func (q *bulkInsertUsersQuery) Eval(ctx context.Context, arg []BulkInsertUsersParams) (int64, error) {
 	q.rows = arg
 	if err := q.ex.Execute(ctx, q); err != nil {
@@ -8,6 +8,9 @@ package db
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 )
@@ -37,6 +40,18 @@ func (q *batchGetUsersQuery) SQL() string {
 	return batchGetUsers
 }
 
+func (q *batchGetUsersQuery) QueryName() string {
+	return "BatchGetUsers"
+}
+
+func (q *batchGetUsersQuery) QueryCmd() string {
+	return ":batchone"
+}
+
+func (q *batchGetUsersQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *batchGetUsersQuery) Args() []any {
 	return nil
 }
@@ -61,6 +76,33 @@ func NewBatchGetUsersQuery(ex QueryExecutor) *batchGetUsersQuery {
 	return &batchGetUsersQuery{ex: ex}
 }
 
+func (q *batchGetUsersQuery) stubArgs() []any {
+	return []any{q.args}
+}
+
+func batchGetUsersScanValues(i User) []any {
+	return []any{
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.Status,
+		&i.Description,
+		&i.CreatedAt,
+	}
+}
+
+func ExpectBatchGetUsers(id []int64, results []ResultOrErr[User]) Step {
+	stubbed := make([]stubBatchResult, len(results))
+	for i, r := range results {
+		if r.Err != nil {
+			stubbed[i].err = r.Err
+			continue
+		}
+		stubbed[i].rows = [][]any{batchGetUsersScanValues(r.Result)}
+	}
+	return stubBatchStep(batchGetUsers, id, stubbed)
+}
+
 func (q *batchGetUsersQuery) Eval(ctx context.Context, id []int64) (*batchGetUsersBatchResults, error) {
 	q.args = id
 	if err := q.ex.Execute(ctx, q); err != nil {
@@ -125,6 +167,18 @@ func (q *batchInsertUsersQuery) SQL() string {
 	return batchInsertUsers
 }
 
+func (q *batchInsertUsersQuery) QueryName() string {
+	return "BatchInsertUsers"
+}
+
+func (q *batchInsertUsersQuery) QueryCmd() string {
+	return ":batchexec"
+}
+
+func (q *batchInsertUsersQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *batchInsertUsersQuery) Args() []any {
 	return nil
 }
@@ -150,6 +204,18 @@ func NewBatchInsertUsersQuery(ex QueryExecutor) *batchInsertUsersQuery {
 	return &batchInsertUsersQuery{ex: ex}
 }
 
+func (q *batchInsertUsersQuery) stubArgs() []any {
+	return []any{q.args}
+}
+
+func ExpectBatchInsertUsers(arg []BatchInsertUsersParams, errs []error) Step {
+	results := make([]stubBatchResult, len(errs))
+	for i, err := range errs {
+		results[i] = stubBatchResult{err: err}
+	}
+	return stubBatchStep(batchInsertUsers, arg, results)
+}
+
 func (q *batchInsertUsersQuery) Eval(ctx context.Context, arg []BatchInsertUsersParams) (*batchInsertUsersBatchResults, error) {
 	q.args = arg
 	if err := q.ex.Execute(ctx, q); err != nil {
@@ -200,6 +266,18 @@ func (q *batchListUsersByEmailQuery) SQL() string {
 	return batchListUsersByEmail
 }
 
+func (q *batchListUsersByEmailQuery) QueryName() string {
+	return "BatchListUsersByEmail"
+}
+
+func (q *batchListUsersByEmailQuery) QueryCmd() string {
+	return ":batchmany"
+}
+
+func (q *batchListUsersByEmailQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *batchListUsersByEmailQuery) Args() []any {
 	return nil
 }
@@ -224,6 +302,35 @@ func NewBatchListUsersByEmailQuery(ex QueryExecutor) *batchListUsersByEmailQuery
 	return &batchListUsersByEmailQuery{ex: ex}
 }
 
+func (q *batchListUsersByEmailQuery) stubArgs() []any {
+	return []any{q.args}
+}
+
+func batchListUsersByEmailScanValues(i User) []any {
+	return []any{
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.Status,
+		&i.Description,
+		&i.CreatedAt,
+	}
+}
+
+func ExpectBatchListUsersByEmail(email []string, results []ResultOrErr[[]User]) Step {
+	stubbed := make([]stubBatchResult, len(results))
+	for i, r := range results {
+		if r.Err != nil {
+			stubbed[i].err = r.Err
+			continue
+		}
+		for _, item := range r.Result {
+			stubbed[i].rows = append(stubbed[i].rows, batchListUsersByEmailScanValues(item))
+		}
+	}
+	return stubBatchStep(batchListUsersByEmail, email, stubbed)
+}
+
 func (q *batchListUsersByEmailQuery) Eval(ctx context.Context, email []string) (*batchListUsersByEmailBatchResults, error) {
 	q.args = email
 	if err := q.ex.Execute(ctx, q); err != nil {
@@ -302,6 +409,18 @@ func (q *batchUpdateEmailsQuery) SQL() string {
 	return batchUpdateEmails
 }
 
+func (q *batchUpdateEmailsQuery) QueryName() string {
+	return "BatchUpdateEmails"
+}
+
+func (q *batchUpdateEmailsQuery) QueryCmd() string {
+	return ":batchexec"
+}
+
+func (q *batchUpdateEmailsQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *batchUpdateEmailsQuery) Args() []any {
 	return nil
 }
@@ -327,6 +446,18 @@ func NewBatchUpdateEmailsQuery(ex QueryExecutor) *batchUpdateEmailsQuery {
 	return &batchUpdateEmailsQuery{ex: ex}
 }
 
+func (q *batchUpdateEmailsQuery) stubArgs() []any {
+	return []any{q.args}
+}
+
+func ExpectBatchUpdateEmails(arg []BatchUpdateEmailsParams, errs []error) Step {
+	results := make([]stubBatchResult, len(errs))
+	for i, err := range errs {
+		results[i] = stubBatchResult{err: err}
+	}
+	return stubBatchStep(batchUpdateEmails, arg, results)
+}
+
 func (q *batchUpdateEmailsQuery) Eval(ctx context.Context, arg []BatchUpdateEmailsParams) (*batchUpdateEmailsBatchResults, error) {
 	q.args = arg
 	if err := q.ex.Execute(ctx, q); err != nil {
@@ -355,3 +486,173 @@ func (b *batchUpdateEmailsBatchResults) Close() error {
 	b.closed = true
 	return b.br.Close()
 }
+
+// Batch queues :one, :many, :exec and :execrows queries. A Batch is a Query:
+// passing it to the Execute method of any QueryExecutor, e.g.
+//
+//	ex.Execute(ctx, NewBatch(NewGetUserQuery(ex).With(id), NewListUsersQuery(ex)))
+//
+// runs the queued queries, through the middleware chain like any other query.
+// Executor sends them to the database in a single round trip, StubExecutor and
+// FakeExecutor run them one at a time. Once the batch ran, every query has its
+// Result, Results or RowsAffected set as if it had been executed on its own.
+// The SQL of each query gets the comment of WithSQLComments, and the batch
+// runs with the shortest timeout of its queries.
+type Batch struct {
+	queries []Query
+}
+
+// NewBatch returns a batch holding queries
+func NewBatch(queries ...Query) *Batch {
+	return &Batch{queries: queries}
+}
+
+// Queue adds queries to the batch, e.g.
+//
+//	b.Queue(NewGetUserQuery(ex).With(id), NewListUsersQuery(ex))
+func (b *Batch) Queue(queries ...Query) *Batch {
+	b.queries = append(b.queries, queries...)
+	return b
+}
+
+// Len returns the number of queued queries
+func (b *Batch) Len() int {
+	return len(b.queries)
+}
+
+// SQL returns the SQL of the queued queries, separated by semicolons
+func (b *Batch) SQL() string {
+	sqls := make([]string, len(b.queries))
+	for i, q := range b.queries {
+		sqls[i] = q.SQL()
+	}
+	return strings.Join(sqls, ";\n")
+}
+
+func (b *Batch) QueryName() string {
+	return "Batch"
+}
+
+func (b *Batch) QueryCmd() string {
+	return ":batch"
+}
+
+func (b *Batch) SourceFile() string {
+	return ""
+}
+
+func (b *Batch) Args() []any {
+	return nil
+}
+
+// Timeout returns the shortest timeout of the queued queries, 0 if none has one
+func (b *Batch) Timeout() time.Duration {
+	var timeout time.Duration
+	for _, q := range b.queries {
+		if q, ok := q.(QueryWithTimeout); ok {
+			if t := q.Timeout(); t > 0 && (timeout == 0 || t < timeout) {
+				timeout = t
+			}
+		}
+	}
+	return timeout
+}
+
+// check reports the first queued query that cannot be part of a batch
+func (b *Batch) check() error {
+	for _, q := range b.queries {
+		switch q.(type) {
+		case QueryOne, QueryStream, QueryMany, QueryExec:
+		default:
+			return errNotBatchable(q)
+		}
+	}
+	return nil
+}
+
+func errNotBatchable(q Query) error {
+	return fmt.Errorf("%s %s queries cannot be queued in a Batch", q.QueryName(), q.QueryCmd())
+}
+
+// build queues every query in a pgx.Batch with the SQL returned by sql
+func (b *Batch) build(sql func(Query) string) *pgx.Batch {
+	batch := &pgx.Batch{}
+	for _, q := range b.queries {
+		batch.Queue(sql(q), q.Args()...)
+	}
+	return batch
+}
+
+// readResults reads the results of every query in order and closes br. It
+// stops at the first failing query and returns its error.
+func (b *Batch) readResults(br pgx.BatchResults) error {
+	err := b.processResults(br)
+	if closeErr := br.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (b *Batch) processResults(br pgx.BatchResults) error {
+	for _, q := range b.queries {
+		if err := processBatchResult(br, q); err != nil {
+			return fmt.Errorf("%s: %w", q.QueryName(), err)
+		}
+	}
+	return nil
+}
+
+func processBatchResult(br pgx.BatchResults, query Query) error {
+	switch q := query.(type) {
+	case QueryOne:
+		return q.Scan(br.QueryRow())
+	case QueryStream:
+		rows, err := br.Query()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			more, err := q.ScanNext(rows)
+			if err != nil || !more {
+				return err
+			}
+		}
+		return rows.Err()
+	case QueryMany:
+		rows, err := br.Query()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			if err := q.ScanRow(rows); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	case QueryExec:
+		result, err := br.Exec()
+		if err != nil {
+			return err
+		}
+		q.SetRowsAffected(result.RowsAffected())
+		return nil
+	default:
+		return errNotBatchable(q)
+	}
+}
+
+// runBatch executes the queued queries one at a time on executors without a
+// database, such as StubExecutor and FakeExecutor
+func runBatch(ctx context.Context, ex QueryExecutor, b *Batch) error {
+	if err := b.check(); err != nil {
+		return err
+	}
+	for _, q := range b.queries {
+		if err := ex.Execute(ctx, q); err != nil {
+			return fmt.Errorf("%s: %w", q.QueryName(), err)
+		}
+	}
+	return nil
+}
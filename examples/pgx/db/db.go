@@ -6,11 +6,21 @@ package db
 
 import (
 	"context"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"net/url"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type DBTX interface {
@@ -26,6 +36,12 @@ type DBTX interface {
 type Query interface {
 	SQL() string
 	Args() []any
+	// QueryName returns the name from the sqlc "-- name:" annotation
+	QueryName() string
+	// QueryCmd returns the sqlc command, e.g. ":one" or ":many"
+	QueryCmd() string
+	// SourceFile returns the query file the query was declared in
+	SourceFile() string
 }
 
 type QueryOne interface {
@@ -38,6 +54,13 @@ type QueryMany interface {
 	ScanRow(row pgx.Row) error
 }
 
+// QueryStream is a :many query that consumes rows one at a time
+type QueryStream interface {
+	Query
+	// ScanNext scans the current row and reports whether to keep reading
+	ScanNext(row pgx.Row) (bool, error)
+}
+
 type QueryExec interface {
 	Query
 	SetRowsAffected(int64)
@@ -53,30 +76,188 @@ type QueryBatch interface {
 	ProcessResults(br pgx.BatchResults) error
 }
 
+// QueryWithTimeout is implemented by queries annotated with a "-- @timeout"
+// comment. Executor.Execute runs them with a context bounded by Timeout.
+type QueryWithTimeout interface {
+	Query
+	Timeout() time.Duration
+}
+
 // QueryExecutor executes queries
 type QueryExecutor interface {
 	Execute(ctx context.Context, query Query) error
 	WithTx(ctx context.Context, fn func(QueryExecutor) error) error
+	WithTxOptions(ctx context.Context, opts TxOptions, fn func(QueryExecutor) error) error
+}
+
+// TxOptions configures transactions started by WithTxOptions
+type TxOptions = pgx.TxOptions
+
+// DBTXWithTxOptions is a DBTX that can begin transactions with options,
+// such as *pgx.Conn and *pgxpool.Pool
+type DBTXWithTxOptions interface {
+	DBTX
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+}
+
+// ExecuteFunc executes a single query
+type ExecuteFunc func(ctx context.Context, query Query) error
+
+// ExecutorMiddleware wraps an ExecuteFunc to add behavior around every Execute call
+type ExecutorMiddleware func(next ExecuteFunc) ExecuteFunc
+
+// ExecutorOption configures an Executor
+type ExecutorOption func(*Executor)
+
+// WithMiddleware adds middlewares to the Executor. The first middleware is the
+// outermost one. Middlewares are also applied to executors created by WithTx.
+func WithMiddleware(mw ...ExecutorMiddleware) ExecutorOption {
+	return func(e *Executor) {
+		e.middlewares = append(e.middlewares, mw...)
+	}
+}
+
+// RetryPolicy controls how WithTx retries transactions that fail with an
+// error reported by IsRetryable
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one
+	MaxAttempts int
+	// Backoff returns the delay before the given retry, starting at 1.
+	// A nil Backoff retries immediately.
+	Backoff func(retry int) time.Duration
+}
+
+// WithRetryPolicy makes WithTx and WithTxOptions re-run fn in a fresh
+// transaction when it fails with a retryable error. Nested transactions are
+// never retried on their own, the outermost transaction is retried instead.
+func WithRetryPolicy(policy RetryPolicy) ExecutorOption {
+	return func(e *Executor) {
+		e.retryPolicy = policy
+	}
+}
+
+// QueryTracer is notified around every query run by an Executor, including
+// queries run inside WithTx. It can be used to record spans or metrics without
+// the generated code depending on a tracing library.
+type QueryTracer interface {
+	// TraceQueryStart is called before the query is sent to the database. The
+	// returned context is used for the query and passed to TraceQueryEnd.
+	TraceQueryStart(ctx context.Context, query Query) context.Context
+	// TraceQueryEnd is called once the query has finished. rowsAffected is
+	// the number of rows returned, affected, copied or queued in a batch.
+	TraceQueryEnd(ctx context.Context, query Query, err error, rowsAffected int64)
+}
+
+// WithTracer sets the QueryTracer of the Executor
+func WithTracer(tracer QueryTracer) ExecutorOption {
+	return func(e *Executor) {
+		e.tracer = tracer
+	}
+}
+
+// WithSQLComments makes the Executor append a sqlcommenter style comment such
+// as /* file='query.sql',query='GetUser' */ to every statement it sends, so
+// the query can be identified in the database activity views and slow logs.
+// tags and tags added to the context with ContextWithSQLCommentTags are
+// included in the comment. The SQL returned by Query.SQL is not changed.
+func WithSQLComments(tags map[string]string) ExecutorOption {
+	return func(e *Executor) {
+		e.sqlComments = true
+		e.sqlCommentTags = tags
+	}
+}
+
+type sqlCommentTagsKey struct{}
+
+// ContextWithSQLCommentTags returns a copy of ctx carrying tags, which are
+// added to the comment of queries run by an Executor created with
+// WithSQLComments. Tags already in ctx are kept unless overwritten.
+func ContextWithSQLCommentTags(ctx context.Context, tags map[string]string) context.Context {
+	merged := make(map[string]string, len(tags))
+	if parent, ok := ctx.Value(sqlCommentTagsKey{}).(map[string]string); ok {
+		for k, v := range parent {
+			merged[k] = v
+		}
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, sqlCommentTagsKey{}, merged)
 }
 
 // Executor implements QueryExecutor using DBTX
 type Executor struct {
-	db DBTX
+	db             DBTX
+	middlewares    []ExecutorMiddleware
+	handler        ExecuteFunc
+	retryPolicy    RetryPolicy
+	tracer         QueryTracer
+	sqlComments    bool
+	sqlCommentTags map[string]string
 }
 
 // NewExecutor creates a new Executor
-func NewExecutor(db DBTX) *Executor {
-	return &Executor{db: db}
+func NewExecutor(db DBTX, opts ...ExecutorOption) *Executor {
+	e := &Executor{db: db}
+	for _, opt := range opts {
+		opt(e)
+	}
+	e.buildHandler()
+	return e
 }
 
-// WithTx executes a function within a transaction
+// withDB returns a copy of the Executor with the same options bound to db
+func (e *Executor) withDB(db DBTX) *Executor {
+	executor := *e
+	executor.db = db
+	executor.retryPolicy = RetryPolicy{}
+	executor.buildHandler()
+	return &executor
+}
+
+// ForTx returns an Executor with the same options that runs queries within
+// tx, a transaction started outside of WithTx
+func (e *Executor) ForTx(tx DBTX) *Executor {
+	return e.withDB(tx)
+}
+
+func (e *Executor) buildHandler() {
+	handler := e.execute
+	for i := len(e.middlewares) - 1; i >= 0; i-- {
+		handler = e.middlewares[i](handler)
+	}
+	e.handler = handler
+}
+
+// WithTx executes a function within a transaction. Calling WithTx on the
+// executor passed to fn, or on an Executor created for a pgx.Tx, runs the
+// nested function within a savepoint instead.
 func (e *Executor) WithTx(ctx context.Context, fn func(QueryExecutor) error) error {
-	tx, err := e.db.Begin(ctx)
+	return e.WithTxOptions(ctx, TxOptions{}, fn)
+}
+
+// WithTxOptions executes a function within a transaction started with opts
+func (e *Executor) WithTxOptions(ctx context.Context, opts TxOptions, fn func(QueryExecutor) error) error {
+	for retry := 1; ; retry++ {
+		err := e.runTx(ctx, opts, fn)
+		if err == nil || retry >= e.retryPolicy.MaxAttempts || !IsRetryable(err) {
+			return err
+		}
+		if e.retryPolicy.Backoff != nil {
+			if err := sleepContext(ctx, e.retryPolicy.Backoff(retry)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (e *Executor) runTx(ctx context.Context, opts TxOptions, fn func(QueryExecutor) error) error {
+	tx, err := e.begin(ctx, opts)
 	if err != nil {
 		return err
 	}
 
-	executor := NewExecutor(tx)
+	executor := e.withDB(tx)
 
 	if err := fn(executor); err != nil {
 		if rbErr := tx.Rollback(ctx); rbErr != nil {
@@ -88,111 +269,1001 @@ func (e *Executor) WithTx(ctx context.Context, fn func(QueryExecutor) error) err
 	return tx.Commit(ctx)
 }
 
+func (e *Executor) begin(ctx context.Context, opts TxOptions) (pgx.Tx, error) {
+	if db, ok := e.db.(DBTXWithTxOptions); ok {
+		return db.BeginTx(ctx, opts)
+	}
+	if opts != (TxOptions{}) {
+		return nil, fmt.Errorf("database does not support transaction options")
+	}
+	return e.db.Begin(ctx)
+}
+
+// IsRetryable reports whether err is a serialization failure (40001) or a
+// deadlock (40P01) that may succeed when the transaction is retried
+func IsRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "40001" || pgErr.Code == "40P01"
+	}
+	return false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Execute runs the query through the middleware chain. Queries implementing
+// QueryWithTimeout run with a context that is canceled after their timeout.
 func (e *Executor) Execute(ctx context.Context, query Query) error {
+	if q, ok := query.(QueryWithTimeout); ok {
+		if timeout := q.Timeout(); timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+	}
+	return e.handler(ctx, query)
+}
+
+func (e *Executor) execute(ctx context.Context, query Query) error {
+	if e.tracer == nil {
+		_, err := e.run(ctx, query)
+		return err
+	}
+	ctx = e.tracer.TraceQueryStart(ctx, query)
+	n, err := e.run(ctx, query)
+	e.tracer.TraceQueryEnd(ctx, query, err, n)
+	return err
+}
+
+// sql returns the SQL sent for query, with the sqlcommenter comment appended
+// when WithSQLComments is used
+func (e *Executor) sql(ctx context.Context, query Query) string {
+	if !e.sqlComments {
+		return query.SQL()
+	}
+	tags := map[string]string{
+		"query": query.QueryName(),
+		"file":  query.SourceFile(),
+	}
+	for k, v := range e.sqlCommentTags {
+		tags[k] = v
+	}
+	if ctxTags, ok := ctx.Value(sqlCommentTagsKey{}).(map[string]string); ok {
+		for k, v := range ctxTags {
+			tags[k] = v
+		}
+	}
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(query.SQL(), " \t\n;"))
+	b.WriteString(" /*")
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(sqlCommentEscape(k))
+		b.WriteString("='")
+		b.WriteString(sqlCommentEscape(tags[k]))
+		b.WriteString("'")
+	}
+	b.WriteString("*/")
+	return b.String()
+}
+
+// sqlCommentEscape URL-encodes s as required by the sqlcommenter spec, which
+// also keeps quotes and comment terminators out of the comment
+func sqlCommentEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// run executes the query and returns the number of rows it returned or affected
+func (e *Executor) run(ctx context.Context, query Query) (int64, error) {
 	switch q := query.(type) {
 	case QueryOne:
-		row := e.db.QueryRow(ctx, q.SQL(), q.Args()...)
-		return q.Scan(row)
+		row := e.db.QueryRow(ctx, e.sql(ctx, q), q.Args()...)
+		if err := q.Scan(row); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	case QueryStream:
+		rows, err := e.db.Query(ctx, e.sql(ctx, q), q.Args()...)
+		if err != nil {
+			return 0, err
+		}
+		defer rows.Close()
+		var n int64
+		for rows.Next() {
+			more, err := q.ScanNext(rows)
+			if err != nil {
+				return n, err
+			}
+			n++
+			if !more {
+				return n, nil
+			}
+		}
+		return n, rows.Err()
 	case QueryMany:
-		rows, err := e.db.Query(ctx, q.SQL(), q.Args()...)
+		rows, err := e.db.Query(ctx, e.sql(ctx, q), q.Args()...)
 		if err != nil {
-			return err
+			return 0, err
 		}
 		defer rows.Close()
+		var n int64
 		for rows.Next() {
 			if err := q.ScanRow(rows); err != nil {
-				return err
+				return n, err
 			}
+			n++
 		}
-		return rows.Err()
+		return n, rows.Err()
 	case QueryExec:
-		result, err := e.db.Exec(ctx, q.SQL(), q.Args()...)
+		result, err := e.db.Exec(ctx, e.sql(ctx, q), q.Args()...)
 		if err != nil {
-			return err
+			return 0, err
 		}
 		q.SetRowsAffected(result.RowsAffected())
-		return nil
+		return result.RowsAffected(), nil
 	case QueryCopyFrom:
 		n, err := e.db.CopyFrom(ctx, q.TableName(), q.ColumnNames(), q.CopyFromSource())
 		if err != nil {
-			return err
+			return n, err
 		}
 		q.SetRowsCopied(n)
-		return nil
+		return n, nil
+	case *Batch:
+		if err := q.check(); err != nil {
+			return 0, err
+		}
+		batch := q.build(func(query Query) string { return e.sql(ctx, query) })
+		br := e.db.SendBatch(ctx, batch)
+		return int64(batch.Len()), q.readResults(br)
 	case QueryBatch:
 		batch := q.BuildBatch()
 		br := e.db.SendBatch(ctx, batch)
-		return q.ProcessResults(br)
+		return int64(batch.Len()), q.ProcessResults(br)
+	case *pipeline:
+		return int64(len(q.queries)), q.run(ctx, e)
 	default:
+		return 0, nil
+	}
+}
+
+// PipelineError reports the queries of a Pipeline call that failed
+type PipelineError struct {
+	// Errs holds the error of each query, in the order they were passed to
+	// Pipeline. It is nil for the queries that succeeded.
+	Errs []error
+}
+
+func (e *PipelineError) Error() string {
+	var msgs []string
+	for _, err := range e.Errs {
+		if err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+	return fmt.Sprintf("pipeline: %d of %d queries failed: %s", len(msgs), len(e.Errs), strings.Join(msgs, "; "))
+}
+
+func (e *PipelineError) Unwrap() []error {
+	var errs []error
+	for _, err := range e.Errs {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// Pipeline sends :one, :many, :exec and :execrows queries in pipeline mode:
+// they are all written to the connection before any response is read, and
+// each one runs in its own implicit transaction, or in the current one within
+// WithTx. Every query gets its Result, Results or RowsAffected set as if it
+// had been executed on its own. When some queries fail, the others still run
+// and the returned *PipelineError holds the error of each query.
+//
+// Pipeline needs the connection behind the executor, which must be a
+// *pgx.Conn, a *pgxpool.Pool or a pgx.Tx. The call runs through the middlewares
+// as a single query named "Pipeline".
+func (e *Executor) Pipeline(ctx context.Context, queries ...Query) error {
+	for _, q := range queries {
+		switch q.(type) {
+		case QueryOne, QueryStream, QueryMany, QueryExec:
+		default:
+			return fmt.Errorf("%s %s queries cannot be sent in a pipeline", q.QueryName(), q.QueryCmd())
+		}
+	}
+	return e.Execute(ctx, &pipeline{queries: queries})
+}
+
+// pipeline is the Query executed by Executor.Pipeline
+type pipeline struct {
+	queries []Query
+}
+
+func (p *pipeline) SQL() string {
+	sqls := make([]string, len(p.queries))
+	for i, q := range p.queries {
+		sqls[i] = q.SQL()
+	}
+	return strings.Join(sqls, ";\n")
+}
+
+func (p *pipeline) Args() []any {
+	return nil
+}
+
+func (p *pipeline) QueryName() string {
+	return "Pipeline"
+}
+
+func (p *pipeline) QueryCmd() string {
+	return ":pipeline"
+}
+
+func (p *pipeline) SourceFile() string {
+	return ""
+}
+
+func (p *pipeline) run(ctx context.Context, e *Executor) error {
+	var conn *pgx.Conn
+	switch db := e.db.(type) {
+	case *pgx.Conn:
+		conn = db
+	case pgx.Tx:
+		conn = db.Conn()
+	case *pgxpool.Pool:
+		return db.AcquireFunc(ctx, func(c *pgxpool.Conn) error {
+			return p.send(ctx, e, c.Conn())
+		})
+	default:
+		return fmt.Errorf("pipeline mode is not supported by %T", e.db)
+	}
+	return p.send(ctx, e, conn)
+}
+
+func (p *pipeline) send(ctx context.Context, e *Executor, conn *pgx.Conn) error {
+	errs := make([]error, len(p.queries))
+	sent := make([]bool, len(p.queries))
+	pl := conn.PgConn().StartPipeline(ctx)
+	var eqb pgx.ExtendedQueryBuilder
+	for i, q := range p.queries {
+		if err := eqb.Build(conn.TypeMap(), nil, q.Args()); err != nil {
+			errs[i] = fmt.Errorf("%s: %w", q.QueryName(), err)
+			continue
+		}
+		pl.SendQueryParams(e.sql(ctx, q), eqb.ParamValues, nil, eqb.ParamFormats, eqb.ResultFormats)
+		pl.SendPipelineSync()
+		sent[i] = true
+	}
+	if err := pl.Flush(); err != nil {
+		pl.Close()
+		return err
+	}
+
+	failed := false
+	for i, q := range p.queries {
+		if !sent[i] {
+			failed = true
+			continue
+		}
+		if err := readPipelineResult(pl, conn.TypeMap(), q); err != nil {
+			errs[i] = fmt.Errorf("%s: %w", q.QueryName(), err)
+			failed = true
+		}
+	}
+	if err := pl.Close(); err != nil {
+		return err
+	}
+	if failed {
+		return &PipelineError{Errs: errs}
+	}
+	return nil
+}
+
+// readPipelineResult reads the results of query up to its pipeline sync
+func readPipelineResult(pl *pgconn.Pipeline, m *pgtype.Map, query Query) error {
+	var queryErr error
+	for {
+		res, err := pl.GetResults()
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if !errors.As(err, &pgErr) {
+				// the connection is broken, no sync will follow
+				return err
+			}
+			queryErr = err
+			continue
+		}
+		switch res := res.(type) {
+		case *pgconn.ResultReader:
+			queryErr = scanPipelineRows(pgx.RowsFromResultReader(m, res), query)
+		case *pgconn.PipelineSync, nil:
+			return queryErr
+		}
+	}
+}
+
+func scanPipelineRows(rows pgx.Rows, query Query) error {
+	defer rows.Close()
+	switch q := query.(type) {
+	case QueryOne:
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			return pgx.ErrNoRows
+		}
+		if err := q.Scan(rows); err != nil {
+			return err
+		}
+	case QueryStream:
+		for rows.Next() {
+			more, err := q.ScanNext(rows)
+			if err != nil {
+				return err
+			}
+			if !more {
+				break
+			}
+		}
+	case QueryMany:
+		for rows.Next() {
+			if err := q.ScanRow(rows); err != nil {
+				return err
+			}
+		}
+	case QueryExec:
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		q.SetRowsAffected(rows.CommandTag().RowsAffected())
 		return nil
 	}
+	rows.Close()
+	return rows.Err()
+}
+
+// ExecuteAll runs independent read queries concurrently on ex and waits for
+// all of them. At most limit queries run at the same time, a limit of 0 or
+// less runs them all at once. The first failing query cancels the context of
+// the others and its error is returned. Queries other than QueryOne and
+// QueryMany, and the ones that must run on the primary (see PrimaryQuery) such
+// as INSERT ... RETURNING or SELECT ... FOR UPDATE, are rejected before any
+// query runs.
+//
+// The limit is deliberately a positional argument rather than an option: the
+// queries take the variadic parameter, and the limit is a property of the
+// call rather than of ex.
+//
+// ExecuteAll is meant for an executor backed by a pool of connections. It must
+// not be used inside WithTx: a transaction is bound to a single connection,
+// which cannot run queries concurrently.
+func ExecuteAll(ctx context.Context, ex QueryExecutor, limit int, queries ...Query) error {
+	for _, q := range queries {
+		switch q.(type) {
+		case QueryOne, QueryMany:
+		default:
+			return fmt.Errorf("%s %s queries cannot be run by ExecuteAll, only :one and :many queries can", q.QueryName(), q.QueryCmd())
+		}
+		if p, ok := q.(PrimaryQuery); ok && p.UsesPrimary() {
+			return fmt.Errorf("%s must run on the primary and cannot be run by ExecuteAll", q.QueryName())
+		}
+	}
+	if limit <= 0 {
+		limit = len(queries)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	sem := make(chan struct{}, limit)
+	for _, q := range queries {
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := ex.Execute(ctx, q); err != nil {
+				once.Do(func() {
+					firstErr = fmt.Errorf("%s: %w", q.QueryName(), err)
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// PrimaryQuery is implemented by :one and :many queries that must run on the
+// primary, such as SELECT ... FOR UPDATE, INSERT ... RETURNING or queries
+// annotated with a "-- @primary" comment
+type PrimaryQuery interface {
+	Query
+	UsesPrimary() bool
+}
+
+// RoutingExecutor implements QueryExecutor on top of a primary database and
+// its read replicas. QueryOne and QueryMany queries run on the replicas in
+// round-robin order, everything else, including transactions, runs on the
+// primary.
+type RoutingExecutor struct {
+	primary  *Executor
+	replicas []*Executor
+	next     atomic.Uint64
+}
+
+// NewRoutingExecutor creates a RoutingExecutor. opts are applied to the
+// executors of the primary and of every replica. Without replicas all queries
+// run on the primary.
+func NewRoutingExecutor(primary DBTX, replicas []DBTX, opts ...ExecutorOption) *RoutingExecutor {
+	r := &RoutingExecutor{primary: NewExecutor(primary, opts...)}
+	for _, db := range replicas {
+		r.replicas = append(r.replicas, NewExecutor(db, opts...))
+	}
+	return r
+}
+
+// Primary returns the Executor of the primary database
+func (r *RoutingExecutor) Primary() *Executor {
+	return r.primary
+}
+
+// ForTx returns the Executor of the primary bound to tx, a transaction started
+// outside of WithTx
+func (r *RoutingExecutor) ForTx(tx DBTX) *Executor {
+	return r.primary.ForTx(tx)
+}
+
+// Execute runs the query on a replica when it only reads data, otherwise on the primary
+func (r *RoutingExecutor) Execute(ctx context.Context, query Query) error {
+	return r.route(query).Execute(ctx, query)
+}
+
+func (r *RoutingExecutor) route(query Query) *Executor {
+	if len(r.replicas) == 0 {
+		return r.primary
+	}
+	if q, ok := query.(PrimaryQuery); ok && q.UsesPrimary() {
+		return r.primary
+	}
+	switch query.(type) {
+	case QueryOne, QueryMany:
+		n := r.next.Add(1) - 1
+		return r.replicas[n%uint64(len(r.replicas))]
+	default:
+		return r.primary
+	}
+}
+
+// WithTx executes a function within a transaction on the primary. Every query
+// executed by fn runs on the primary as well.
+func (r *RoutingExecutor) WithTx(ctx context.Context, fn func(QueryExecutor) error) error {
+	return r.primary.WithTx(ctx, fn)
+}
+
+// WithTxOptions executes a function within a transaction on the primary started with opts
+func (r *RoutingExecutor) WithTxOptions(ctx context.Context, opts TxOptions, fn func(QueryExecutor) error) error {
+	return r.primary.WithTxOptions(ctx, opts, fn)
 }
 
 // Compile-time interface checks
 var (
-	_ DBTX = (*pgx.Conn)(nil)
-	_ DBTX = (pgx.Tx)(nil)
+	_ DBTX              = (*pgx.Conn)(nil)
+	_ DBTX              = (pgx.Tx)(nil)
+	_ DBTXWithTxOptions = (*pgx.Conn)(nil)
+	_ QueryExecutor     = (*Executor)(nil)
+	_ QueryExecutor     = (*RoutingExecutor)(nil)
 )
 
 type Step struct {
-	SQL   string
-	Args  []any
+	SQL string
+	// Args are compared with the query arguments using reflect.DeepEqual,
+	// except for ArgMatcher values, which match the argument themselves.
+	Args []any
+	// Apply is called with nil for the transaction steps created by
+	// ExpectBegin, ExpectCommit and ExpectRollback.
 	Apply func(Query) error
+
+	tx        string
+	times     int
+	hasTimes  bool
+	anyTimes  bool
+	unordered bool
+	group     []Step
+}
+
+// ExpectBegin expects WithTx to start a transaction. Once a stub has
+// transaction steps, every WithTx call must match ExpectBegin followed by
+// ExpectCommit, or ExpectRollback when its callback fails.
+func ExpectBegin() Step {
+	return Step{tx: "begin"}
+}
+
+// ExpectCommit expects WithTx to commit after its callback succeeded
+func ExpectCommit() Step {
+	return Step{tx: "commit"}
+}
+
+// ExpectRollback expects WithTx to roll back after its callback failed
+func ExpectRollback() Step {
+	return Step{tx: "rollback"}
+}
+
+// WillReturnError makes the step fail with err
+func (s Step) WillReturnError(err error) Step {
+	s.Apply = func(Query) error { return err }
+	return s
+}
+
+// ArgMatcher matches a single query argument of a Step
+type ArgMatcher interface {
+	Match(arg any) bool
+}
+
+type argMatcher struct {
+	desc  string
+	match func(any) bool
+}
+
+func (m argMatcher) Match(arg any) bool { return m.match(arg) }
+func (m argMatcher) String() string     { return m.desc }
+
+// Any matches every argument
+func Any() ArgMatcher {
+	return argMatcher{desc: "Any()", match: func(any) bool { return true }}
+}
+
+// Eq matches arguments deeply equal to want
+func Eq(want any) ArgMatcher {
+	return argMatcher{
+		desc:  fmt.Sprintf("Eq(%v)", want),
+		match: func(arg any) bool { return reflect.DeepEqual(arg, want) },
+	}
+}
+
+// ArgThat matches arguments for which fn returns true
+func ArgThat(fn func(arg any) bool) ArgMatcher {
+	return argMatcher{desc: "ArgThat(...)", match: fn}
+}
+
+// WithinDuration matches time arguments no further than d from want. It
+// accepts time.Time, *time.Time and driver.Valuer implementations producing a
+// time.Time, such as nullable time types.
+func WithinDuration(want time.Time, d time.Duration) ArgMatcher {
+	return argMatcher{
+		desc: fmt.Sprintf("WithinDuration(%v, %v)", want, d),
+		match: func(arg any) bool {
+			if v, ok := arg.(driver.Valuer); ok {
+				value, err := v.Value()
+				if err != nil {
+					return false
+				}
+				arg = value
+			}
+			var got time.Time
+			switch v := arg.(type) {
+			case time.Time:
+				got = v
+			case *time.Time:
+				if v == nil {
+					return false
+				}
+				got = *v
+			default:
+				return false
+			}
+			diff := got.Sub(want)
+			return diff >= -d && diff <= d
+		},
+	}
+}
+
+func argsMatch(want, got []any) bool {
+	if len(want) != len(got) {
+		return false
+	}
+	for i := range want {
+		if m, ok := want[i].(ArgMatcher); ok {
+			if !m.Match(got[i]) {
+				return false
+			}
+		} else if !reflect.DeepEqual(want[i], got[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Times expects the step to run exactly n times instead of once. Times(0)
+// expects the step to never run, negative values fail the test.
+func (s Step) Times(n int) Step {
+	s.times = n
+	s.hasTimes = true
+	s.anyTimes = false
+	return s
+}
+
+// AnyTimes expects the step to run any number of times, including never
+func (s Step) AnyTimes() Step {
+	s.anyTimes = true
+	return s
+}
+
+// InAnyOrder groups steps that may run in any order among themselves, e.g.
+// queries issued by concurrent goroutines. The group keeps its position in the
+// sequence of steps.
+func InAnyOrder(steps ...Step) Step {
+	return Step{unordered: true, group: steps}
+}
+
+type expectation struct {
+	step  Step
+	calls int
+}
+
+func (e *expectation) min() int {
+	switch {
+	case e.step.anyTimes:
+		return 0
+	case e.step.hasTimes:
+		return max(e.step.times, 0)
+	default:
+		return 1
+	}
+}
+
+func (e *expectation) exhausted() bool {
+	return !e.step.anyTimes && e.calls >= e.min()
+}
+
+func (e *expectation) matches(q Query) bool {
+	return e.step.tx == "" && e.step.SQL == q.SQL() && argsMatch(e.step.Args, queryArgs(q))
+}
+
+// queryArgs returns the arguments steps are matched against. Batch and
+// copyfrom queries have no Args and are matched against the slice passed to
+// Eval instead.
+func queryArgs(q Query) []any {
+	if q, ok := q.(interface{ stubArgs() []any }); ok {
+		return q.stubArgs()
+	}
+	return q.Args()
+}
+
+func expectationsOf(step Step) []*expectation {
+	if !step.unordered {
+		return []*expectation{{step: step}}
+	}
+	var out []*expectation
+	for _, s := range step.group {
+		out = append(out, expectationsOf(s)...)
+	}
+	return out
+}
+
+// stubGroup holds the expectations of one position in the sequence: a single
+// step in strict order, or all steps of an InAnyOrder group.
+type stubGroup []*expectation
+
+func (g stubGroup) satisfied() bool {
+	for _, e := range g {
+		if e.calls < e.min() {
+			return false
+		}
+	}
+	return true
+}
+
+func (g stubGroup) exhausted() bool {
+	for _, e := range g {
+		if !e.exhausted() {
+			return false
+		}
+	}
+	return true
 }
 
 // StubExecutor verifies queries are executed in expected order with expected arguments.
+// It is safe for concurrent use.
 type StubExecutor struct {
 	t interface {
 		Helper()
 		Errorf(format string, args ...any)
 	}
-	steps []Step
-	i     int
+	mu        sync.Mutex
+	groups    []stubGroup
+	i         int
+	txSteps   bool
+	txOptions []TxOptions
 }
 
 func NewStubExecutor(t interface {
 	Helper()
 	Errorf(format string, args ...any)
 }, steps ...Step) *StubExecutor {
-	return &StubExecutor{t: t, steps: steps}
+	s := &StubExecutor{t: t}
+	for _, step := range steps {
+		group := expectationsOf(step)
+		for _, e := range group {
+			s.txSteps = s.txSteps || e.step.tx != ""
+			if e.step.times < 0 {
+				t.Errorf("invalid step: Times(%d) for SQL=%q, use Times(0) for a step that must not run", e.step.times, e.step.SQL)
+			}
+		}
+		s.groups = append(s.groups, group)
+	}
+	return s
 }
 
 func (s *StubExecutor) Execute(ctx context.Context, q Query) error {
 	s.t.Helper()
+	if b, ok := q.(*Batch); ok {
+		return runBatch(ctx, s, b)
+	}
 
-	if s.i >= len(s.steps) {
-		s.t.Errorf("unexpected query: SQL=%q Args=%v", q.SQL(), q.Args())
-		return fmt.Errorf("unexpected query")
+	step, err := s.next(q)
+	if err != nil {
+		return err
+	}
+	if step.Apply != nil {
+		return step.Apply(q)
 	}
+	return nil
+}
 
-	step := s.steps[s.i]
-	s.i++
+// consume marks the first pending step accepted by match as executed. Groups
+// whose steps already ran often enough are skipped when match accepts none of
+// their steps. The caller must hold s.mu.
+func (s *StubExecutor) consume(match func(*expectation) bool) (Step, bool) {
+	for ; s.i < len(s.groups); s.i++ {
+		group := s.groups[s.i]
+		for _, e := range group {
+			if !e.exhausted() && match(e) {
+				e.calls++
+				if group.exhausted() {
+					s.i++
+				}
+				return e.step, true
+			}
+		}
+		if !group.satisfied() {
+			break
+		}
+	}
+	return Step{}, false
+}
+
+func (s *StubExecutor) next(q Query) (Step, error) {
+	s.t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
+	if step, ok := s.consume(func(e *expectation) bool { return e.matches(q) }); ok {
+		return step, nil
+	}
+
+	if s.i >= len(s.groups) {
+		s.t.Errorf("unexpected query: SQL=%q Args=%v", q.SQL(), queryArgs(q))
+		return Step{}, fmt.Errorf("unexpected query")
+	}
+
+	group := s.groups[s.i]
+	if len(group) > 1 {
+		s.t.Errorf("no step of the unordered group at step %d matches query: SQL=%q Args=%v", s.i+1, q.SQL(), queryArgs(q))
+		return Step{}, fmt.Errorf("unexpected query")
+	}
+	step := group[0].step
+	if step.tx != "" {
+		s.t.Errorf("expected %s at step %d, got query: SQL=%q Args=%v", step.tx, s.i+1, q.SQL(), queryArgs(q))
+		return Step{}, fmt.Errorf("unexpected query")
+	}
 	if q.SQL() != step.SQL {
-		s.t.Errorf("SQL mismatch at step %d:\nwant: %q\ngot:  %q", s.i, step.SQL, q.SQL())
-		return fmt.Errorf("SQL mismatch")
+		s.t.Errorf("SQL mismatch at step %d:\nwant: %q\ngot:  %q", s.i+1, step.SQL, q.SQL())
+		return Step{}, fmt.Errorf("SQL mismatch")
 	}
+	s.t.Errorf("Args mismatch at step %d:\nwant: %v\ngot:  %v", s.i+1, step.Args, queryArgs(q))
+	return Step{}, fmt.Errorf("args mismatch")
+}
 
-	if !reflect.DeepEqual(q.Args(), step.Args) {
-		s.t.Errorf("Args mismatch at step %d:\nwant: %v\ngot:  %v", s.i, step.Args, q.Args())
-		return fmt.Errorf("args mismatch")
+// expectTx consumes the transaction step for event, unless the stub has no
+// transaction steps at all
+func (s *StubExecutor) expectTx(event string) error {
+	s.t.Helper()
+	s.mu.Lock()
+	if !s.txSteps {
+		s.mu.Unlock()
+		return nil
 	}
+	step, ok := s.consume(func(e *expectation) bool { return e.step.tx == event })
+	i := s.i
+	s.mu.Unlock()
 
+	if !ok {
+		s.t.Errorf("unexpected %s at step %d", event, i+1)
+		return fmt.Errorf("unexpected %s", event)
+	}
 	if step.Apply != nil {
-		return step.Apply(q)
+		return step.Apply(nil)
 	}
 	return nil
 }
 
 func (s *StubExecutor) AssertDone() {
 	s.t.Helper()
-	if s.i != len(s.steps) {
-		s.t.Errorf("not all steps executed: %d/%d", s.i, len(s.steps))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	done := 0
+	for _, group := range s.groups {
+		if group.satisfied() {
+			done++
+		}
+	}
+	if done != len(s.groups) {
+		s.t.Errorf("not all steps executed: %d/%d", done, len(s.groups))
 	}
 }
 
 func (s *StubExecutor) WithTx(ctx context.Context, fn func(QueryExecutor) error) error {
-	return fn(s)
+	return s.WithTxOptions(ctx, TxOptions{}, fn)
+}
+
+// WithTxOptions records opts and runs fn against the stub itself. When the
+// stub has transaction steps, it also checks that the transaction begins and
+// then commits, or rolls back when fn fails.
+func (s *StubExecutor) WithTxOptions(ctx context.Context, opts TxOptions, fn func(QueryExecutor) error) error {
+	s.t.Helper()
+	s.mu.Lock()
+	s.txOptions = append(s.txOptions, opts)
+	s.mu.Unlock()
+
+	if err := s.expectTx("begin"); err != nil {
+		return err
+	}
+	if err := fn(s); err != nil {
+		if rbErr := s.expectTx("rollback"); rbErr != nil {
+			return fmt.Errorf("tx failed: %w, rollback failed: %v", err, rbErr)
+		}
+		return err
+	}
+	return s.expectTx("commit")
+}
+
+// TxOptions returns the options of every transaction started on the stub, in order
+func (s *StubExecutor) TxOptions() []TxOptions {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]TxOptions(nil), s.txOptions...)
+}
+
+// ResultOrErr is the stubbed outcome of one query queued in a batch
+type ResultOrErr[T any] struct {
+	Result T
+	Err    error
 }
 
+type stubBatchResult struct {
+	// rows holds the values of each row as pointers, in scan order
+	rows [][]any
+	err  error
+}
+
+func stubBatchStep(sql string, args any, results []stubBatchResult) Step {
+	return Step{
+		SQL:  sql,
+		Args: []any{args},
+		Apply: func(q Query) error {
+			return q.(QueryBatch).ProcessResults(&stubBatchResults{results: results})
+		},
+	}
+}
+
+// stubBatchResults serves stubbed results to the ProcessResults of a batch query
+type stubBatchResults struct {
+	pgx.BatchResults
+	results []stubBatchResult
+}
+
+func (b *stubBatchResults) next() stubBatchResult {
+	if len(b.results) == 0 {
+		return stubBatchResult{err: errors.New("no stubbed result left in batch")}
+	}
+	r := b.results[0]
+	b.results = b.results[1:]
+	return r
+}
+
+func (b *stubBatchResults) Exec() (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, b.next().err
+}
+
+func (b *stubBatchResults) Query() (pgx.Rows, error) {
+	r := b.next()
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &stubRows{rows: r.rows}, nil
+}
+
+func (b *stubBatchResults) QueryRow() pgx.Row {
+	r := b.next()
+	if r.err == nil && len(r.rows) == 0 {
+		r.err = pgx.ErrNoRows
+	}
+	return &stubRows{rows: r.rows, err: r.err}
+}
+
+func (b *stubBatchResults) Close() error {
+	return nil
+}
+
+// stubRows copies stubbed values into the destinations passed to Scan
+type stubRows struct {
+	pgx.Rows
+	rows [][]any
+	cur  []any
+	err  error
+}
+
+func (r *stubRows) Next() bool {
+	if r.err != nil || len(r.rows) == 0 {
+		return false
+	}
+	r.cur, r.rows = r.rows[0], r.rows[1:]
+	return true
+}
+
+func (r *stubRows) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.cur == nil && !r.Next() {
+		return pgx.ErrNoRows
+	}
+	if len(dest) != len(r.cur) {
+		return fmt.Errorf("scanning %d stubbed values into %d destinations", len(r.cur), len(dest))
+	}
+	for i, v := range r.cur {
+		reflect.ValueOf(dest[i]).Elem().Set(reflect.ValueOf(v).Elem())
+	}
+	return nil
+}
+
+func (r *stubRows) Err() error {
+	return r.err
+}
+
+func (r *stubRows) Close() {}
+
 var _ QueryExecutor = (*StubExecutor)(nil)
@@ -7,8 +7,13 @@ package db
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"iter"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const countUsers = `-- name: CountUsers :one
@@ -24,6 +29,18 @@ func (q *CountUsersQuery) SQL() string {
 	return countUsers
 }
 
+func (q *CountUsersQuery) QueryName() string {
+	return "CountUsers"
+}
+
+func (q *CountUsersQuery) QueryCmd() string {
+	return ":one"
+}
+
+func (q *CountUsersQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *CountUsersQuery) Args() []any {
 	return nil
 }
@@ -79,6 +96,23 @@ func (q *CreatePostQuery) SQL() string {
 	return createPost
 }
 
+func (q *CreatePostQuery) QueryName() string {
+	return "CreatePost"
+}
+
+func (q *CreatePostQuery) QueryCmd() string {
+	return ":one"
+}
+
+func (q *CreatePostQuery) SourceFile() string {
+	return "query.sql"
+}
+
+// UsesPrimary makes RoutingExecutor run the query on the primary
+func (q *CreatePostQuery) UsesPrimary() bool {
+	return true
+}
+
 func (q *CreatePostQuery) Args() []any {
 	return []any{q.arg.AuthorID, q.arg.Title, q.arg.Body}
 }
@@ -105,6 +139,12 @@ func (q *CreatePostQuery) Eval(ctx context.Context, arg CreatePostParams) (Post,
 	return q.Result, nil
 }
 
+// With sets the arguments of the query without executing it, e.g. to queue it in a Batch
+func (q *CreatePostQuery) With(arg CreatePostParams) *CreatePostQuery {
+	q.arg = arg
+	return q
+}
+
 func NewCreatePostQuery(ex QueryExecutor) *CreatePostQuery {
 	return &CreatePostQuery{ex: ex}
 }
@@ -119,6 +159,17 @@ func ExpectCreatePost(arg CreatePostParams, result Post, err error) Step {
 	}
 }
 
+func ExpectCreatePostMatching(authorID, title, body ArgMatcher, result Post, err error) Step {
+	return Step{
+		SQL:  createPost,
+		Args: []any{authorID, title, body},
+		Apply: func(q Query) error {
+			q.(*CreatePostQuery).SetResult(result)
+			return err
+		},
+	}
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (name, email, status)
 VALUES ($1, $2, $3)
@@ -141,6 +192,23 @@ func (q *CreateUserQuery) SQL() string {
 	return createUser
 }
 
+func (q *CreateUserQuery) QueryName() string {
+	return "CreateUser"
+}
+
+func (q *CreateUserQuery) QueryCmd() string {
+	return ":one"
+}
+
+func (q *CreateUserQuery) SourceFile() string {
+	return "query.sql"
+}
+
+// UsesPrimary makes RoutingExecutor run the query on the primary
+func (q *CreateUserQuery) UsesPrimary() bool {
+	return true
+}
+
 func (q *CreateUserQuery) Args() []any {
 	return []any{q.arg.Name, q.arg.Email, q.arg.Status}
 }
@@ -168,6 +236,12 @@ func (q *CreateUserQuery) Eval(ctx context.Context, arg CreateUserParams) (User,
 	return q.Result, nil
 }
 
+// With sets the arguments of the query without executing it, e.g. to queue it in a Batch
+func (q *CreateUserQuery) With(arg CreateUserParams) *CreateUserQuery {
+	q.arg = arg
+	return q
+}
+
 func NewCreateUserQuery(ex QueryExecutor) *CreateUserQuery {
 	return &CreateUserQuery{ex: ex}
 }
@@ -182,6 +256,17 @@ func ExpectCreateUser(arg CreateUserParams, result User, err error) Step {
 	}
 }
 
+func ExpectCreateUserMatching(name, email, status ArgMatcher, result User, err error) Step {
+	return Step{
+		SQL:  createUser,
+		Args: []any{name, email, status},
+		Apply: func(q Query) error {
+			q.(*CreateUserQuery).SetResult(result)
+			return err
+		},
+	}
+}
+
 const deleteUser = `-- name: DeleteUser :exec
 DELETE FROM users
 WHERE id = $1
@@ -197,6 +282,18 @@ func (q *DeleteUserQuery) SQL() string {
 	return deleteUser
 }
 
+func (q *DeleteUserQuery) QueryName() string {
+	return "DeleteUser"
+}
+
+func (q *DeleteUserQuery) QueryCmd() string {
+	return ":exec"
+}
+
+func (q *DeleteUserQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *DeleteUserQuery) Args() []any {
 	return []any{q.id}
 }
@@ -209,6 +306,12 @@ func (q *DeleteUserQuery) Eval(ctx context.Context, id int64) error {
 	return q.ex.Execute(ctx, q)
 }
 
+// With sets the arguments of the query without executing it, e.g. to queue it in a Batch
+func (q *DeleteUserQuery) With(id int64) *DeleteUserQuery {
+	q.id = id
+	return q
+}
+
 func NewDeleteUserQuery(ex QueryExecutor) *DeleteUserQuery {
 	return &DeleteUserQuery{ex: ex}
 }
@@ -222,6 +325,16 @@ func ExpectDeleteUser(id int64, err error) Step {
 	}
 }
 
+func ExpectDeleteUserMatching(id ArgMatcher, err error) Step {
+	return Step{
+		SQL:  deleteUser,
+		Args: []any{id},
+		Apply: func(q Query) error {
+			return err
+		},
+	}
+}
+
 const getPostWithAuthor = `-- name: GetPostWithAuthor :one
 SELECT posts.id, posts.author_id, posts.title, posts.body, posts.created_at, users.id, users.name, users.email, users.status, users.description, users.created_at
 FROM posts
@@ -244,6 +357,18 @@ func (q *GetPostWithAuthorQuery) SQL() string {
 	return getPostWithAuthor
 }
 
+func (q *GetPostWithAuthorQuery) QueryName() string {
+	return "GetPostWithAuthor"
+}
+
+func (q *GetPostWithAuthorQuery) QueryCmd() string {
+	return ":one"
+}
+
+func (q *GetPostWithAuthorQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *GetPostWithAuthorQuery) Args() []any {
 	return []any{q.id}
 }
@@ -276,6 +401,12 @@ func (q *GetPostWithAuthorQuery) Eval(ctx context.Context, id int64) (GetPostWit
 	return q.Result, nil
 }
 
+// With sets the arguments of the query without executing it, e.g. to queue it in a Batch
+func (q *GetPostWithAuthorQuery) With(id int64) *GetPostWithAuthorQuery {
+	q.id = id
+	return q
+}
+
 func NewGetPostWithAuthorQuery(ex QueryExecutor) *GetPostWithAuthorQuery {
 	return &GetPostWithAuthorQuery{ex: ex}
 }
@@ -290,6 +421,17 @@ func ExpectGetPostWithAuthor(id int64, result GetPostWithAuthorRow, err error) S
 	}
 }
 
+func ExpectGetPostWithAuthorMatching(id ArgMatcher, result GetPostWithAuthorRow, err error) Step {
+	return Step{
+		SQL:  getPostWithAuthor,
+		Args: []any{id},
+		Apply: func(q Query) error {
+			q.(*GetPostWithAuthorQuery).SetResult(result)
+			return err
+		},
+	}
+}
+
 const getUser = `-- name: GetUser :one
 SELECT id, name, email, status, description, created_at FROM users
 WHERE id = $1
@@ -305,6 +447,18 @@ func (q *GetUserQuery) SQL() string {
 	return getUser
 }
 
+func (q *GetUserQuery) QueryName() string {
+	return "GetUser"
+}
+
+func (q *GetUserQuery) QueryCmd() string {
+	return ":one"
+}
+
+func (q *GetUserQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *GetUserQuery) Args() []any {
 	return []any{q.id}
 }
@@ -332,6 +486,12 @@ func (q *GetUserQuery) Eval(ctx context.Context, id int64) (User, error) {
 	return q.Result, nil
 }
 
+// With sets the arguments of the query without executing it, e.g. to queue it in a Batch
+func (q *GetUserQuery) With(id int64) *GetUserQuery {
+	q.id = id
+	return q
+}
+
 func NewGetUserQuery(ex QueryExecutor) *GetUserQuery {
 	return &GetUserQuery{ex: ex}
 }
@@ -346,6 +506,17 @@ func ExpectGetUser(id int64, result User, err error) Step {
 	}
 }
 
+func ExpectGetUserMatching(id ArgMatcher, result User, err error) Step {
+	return Step{
+		SQL:  getUser,
+		Args: []any{id},
+		Apply: func(q Query) error {
+			q.(*GetUserQuery).SetResult(result)
+			return err
+		},
+	}
+}
+
 const getUserForUpdate = `-- name: GetUserForUpdate :one
 SELECT id, name, email, status, description, created_at FROM users
 WHERE id = $1
@@ -362,6 +533,23 @@ func (q *GetUserForUpdateQuery) SQL() string {
 	return getUserForUpdate
 }
 
+func (q *GetUserForUpdateQuery) QueryName() string {
+	return "GetUserForUpdate"
+}
+
+func (q *GetUserForUpdateQuery) QueryCmd() string {
+	return ":one"
+}
+
+func (q *GetUserForUpdateQuery) SourceFile() string {
+	return "query.sql"
+}
+
+// UsesPrimary makes RoutingExecutor run the query on the primary
+func (q *GetUserForUpdateQuery) UsesPrimary() bool {
+	return true
+}
+
 func (q *GetUserForUpdateQuery) Args() []any {
 	return []any{q.id}
 }
@@ -389,6 +577,12 @@ func (q *GetUserForUpdateQuery) Eval(ctx context.Context, id int64) (User, error
 	return q.Result, nil
 }
 
+// With sets the arguments of the query without executing it, e.g. to queue it in a Batch
+func (q *GetUserForUpdateQuery) With(id int64) *GetUserForUpdateQuery {
+	q.id = id
+	return q
+}
+
 func NewGetUserForUpdateQuery(ex QueryExecutor) *GetUserForUpdateQuery {
 	return &GetUserForUpdateQuery{ex: ex}
 }
@@ -403,6 +597,17 @@ func ExpectGetUserForUpdate(id int64, result User, err error) Step {
 	}
 }
 
+func ExpectGetUserForUpdateMatching(id ArgMatcher, result User, err error) Step {
+	return Step{
+		SQL:  getUserForUpdate,
+		Args: []any{id},
+		Apply: func(q Query) error {
+			q.(*GetUserForUpdateQuery).SetResult(result)
+			return err
+		},
+	}
+}
+
 const listPostsWithAuthor = `-- name: ListPostsWithAuthor :many
 SELECT posts.id, posts.author_id, posts.title, posts.body, posts.created_at, users.id, users.name, users.email, users.status, users.description, users.created_at
 FROM posts
@@ -424,6 +629,18 @@ func (q *ListPostsWithAuthorQuery) SQL() string {
 	return listPostsWithAuthor
 }
 
+func (q *ListPostsWithAuthorQuery) QueryName() string {
+	return "ListPostsWithAuthor"
+}
+
+func (q *ListPostsWithAuthorQuery) QueryCmd() string {
+	return ":many"
+}
+
+func (q *ListPostsWithAuthorQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *ListPostsWithAuthorQuery) Args() []any {
 	return nil
 }
@@ -461,6 +678,55 @@ func (q *ListPostsWithAuthorQuery) Eval(ctx context.Context) ([]ListPostsWithAut
 	return q.Results, nil
 }
 
+// listPostsWithAuthorStream hands ListPostsWithAuthor rows to an Iter loop one at a time.
+type listPostsWithAuthorStream struct {
+	*ListPostsWithAuthorQuery
+	yield   func(ListPostsWithAuthorRow, error) bool
+	stopped bool
+}
+
+func (s *listPostsWithAuthorStream) ScanNext(row pgx.Row) (bool, error) {
+	var i ListPostsWithAuthorRow
+	if err := row.Scan(
+		&i.Post.ID,
+		&i.Post.AuthorID,
+		&i.Post.Title,
+		&i.Post.Body,
+		&i.Post.CreatedAt,
+		&i.User.ID,
+		&i.User.Name,
+		&i.User.Email,
+		&i.User.Status,
+		&i.User.Description,
+		&i.User.CreatedAt,
+	); err != nil {
+		return false, err
+	}
+	s.stopped = !s.yield(i, nil)
+	return !s.stopped, nil
+}
+
+// SetResults yields results provided by a StubExecutor step.
+func (s *listPostsWithAuthorStream) SetResults(results []ListPostsWithAuthorRow) {
+	for _, r := range results {
+		if s.stopped = !s.yield(r, nil); s.stopped {
+			return
+		}
+	}
+}
+
+// Iter executes the query and yields rows as they are scanned. The rows are
+// closed when the loop finishes or breaks early.
+func (q *ListPostsWithAuthorQuery) Iter(ctx context.Context) iter.Seq2[ListPostsWithAuthorRow, error] {
+	return func(yield func(ListPostsWithAuthorRow, error) bool) {
+		s := &listPostsWithAuthorStream{ListPostsWithAuthorQuery: q, yield: yield}
+		if err := q.ex.Execute(ctx, s); err != nil && !s.stopped {
+			var zero ListPostsWithAuthorRow
+			yield(zero, err)
+		}
+	}
+}
+
 func NewListPostsWithAuthorQuery(ex QueryExecutor) *ListPostsWithAuthorQuery {
 	return &ListPostsWithAuthorQuery{ex: ex}
 }
@@ -469,7 +735,9 @@ func ExpectListPostsWithAuthor(results []ListPostsWithAuthorRow, err error) Step
 		SQL:  listPostsWithAuthor,
 		Args: nil,
 		Apply: func(q Query) error {
-			q.(*ListPostsWithAuthorQuery).SetResults(results)
+			q.(interface {
+				SetResults([]ListPostsWithAuthorRow)
+			}).SetResults(results)
 			return err
 		},
 	}
@@ -489,6 +757,18 @@ func (q *ListUsersQuery) SQL() string {
 	return listUsers
 }
 
+func (q *ListUsersQuery) QueryName() string {
+	return "ListUsers"
+}
+
+func (q *ListUsersQuery) QueryCmd() string {
+	return ":many"
+}
+
+func (q *ListUsersQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *ListUsersQuery) Args() []any {
 	return nil
 }
@@ -521,15 +801,199 @@ func (q *ListUsersQuery) Eval(ctx context.Context) ([]User, error) {
 	return q.Results, nil
 }
 
+// listUsersStream hands ListUsers rows to an Iter loop one at a time.
+type listUsersStream struct {
+	*ListUsersQuery
+	yield   func(User, error) bool
+	stopped bool
+}
+
+func (s *listUsersStream) ScanNext(row pgx.Row) (bool, error) {
+	var i User
+	if err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.Status,
+		&i.Description,
+		&i.CreatedAt,
+	); err != nil {
+		return false, err
+	}
+	s.stopped = !s.yield(i, nil)
+	return !s.stopped, nil
+}
+
+// SetResults yields results provided by a StubExecutor step.
+func (s *listUsersStream) SetResults(results []User) {
+	for _, r := range results {
+		if s.stopped = !s.yield(r, nil); s.stopped {
+			return
+		}
+	}
+}
+
+// Iter executes the query and yields rows as they are scanned. The rows are
+// closed when the loop finishes or breaks early.
+func (q *ListUsersQuery) Iter(ctx context.Context) iter.Seq2[User, error] {
+	return func(yield func(User, error) bool) {
+		s := &listUsersStream{ListUsersQuery: q, yield: yield}
+		if err := q.ex.Execute(ctx, s); err != nil && !s.stopped {
+			var zero User
+			yield(zero, err)
+		}
+	}
+}
+
 func NewListUsersQuery(ex QueryExecutor) *ListUsersQuery {
 	return &ListUsersQuery{ex: ex}
 }
+
+const listUsersFirstPage = `-- name: ListUsers :many
+SELECT * FROM (
+SELECT id, name, email, status, description, created_at FROM users
+ORDER BY created_at DESC
+) AS page
+ORDER BY created_at DESC, id DESC
+LIMIT $1
+`
+
+const listUsersNextPage = `-- name: ListUsers :many
+SELECT * FROM (
+SELECT id, name, email, status, description, created_at FROM users
+ORDER BY created_at DESC
+) AS page
+WHERE (created_at, id) < ($1, $2)
+ORDER BY created_at DESC, id DESC
+LIMIT $3
+`
+
+// ListUsersPage is a page of ListUsers rows returned by EvalPage
+type ListUsersPage struct {
+	Items []User
+	// NextCursor reads the following page, it is empty on the last page
+	NextCursor string
+	HasMore    bool
+}
+
+// EncodeListUsersCursor returns the opaque cursor of the page following
+// the row with the given keyset values
+func EncodeListUsersCursor(createdAt pgtype.Timestamptz, id int64) (string, error) {
+	data, err := json.Marshal([]any{createdAt, id})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeListUsersCursor returns the keyset values of a cursor created by
+// EncodeListUsersCursor
+func DecodeListUsersCursor(cursor string) (createdAt pgtype.Timestamptz, id int64, err error) {
+	var values []json.RawMessage
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil {
+		err = json.Unmarshal(data, &values)
+	}
+	if err == nil && len(values) != 2 {
+		err = fmt.Errorf("got %d values, want 2", len(values))
+	}
+	if err == nil {
+		err = json.Unmarshal(values[0], &createdAt)
+	}
+	if err == nil {
+		err = json.Unmarshal(values[1], &id)
+	}
+	if err != nil {
+		err = fmt.Errorf("invalid ListUsers cursor: %w", err)
+	}
+	return createdAt, id, err
+}
+
+// listUsersPageQuery reads a single page of ListUsers
+type listUsersPageQuery struct {
+	*ListUsersQuery
+	after  string
+	cursor []any
+	limit  int
+}
+
+// newListUsersPageQuery returns the query reading the page of q
+// following cursor
+func newListUsersPageQuery(q *ListUsersQuery, cursor string, limit int) (*listUsersPageQuery, error) {
+	page := &listUsersPageQuery{ListUsersQuery: q, after: cursor, limit: limit}
+	if cursor != "" {
+		createdAt, id, err := DecodeListUsersCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		page.cursor = []any{createdAt, id}
+	}
+	return page, nil
+}
+
+func (q *listUsersPageQuery) SQL() string {
+	if q.cursor != nil {
+		return listUsersNextPage
+	}
+	return listUsersFirstPage
+}
+
+func (q *listUsersPageQuery) Args() []any {
+	args := append(q.ListUsersQuery.Args(), q.cursor...)
+	return append(args, q.limit+1)
+}
+
+// EvalPage returns at most limit rows following cursor, sorted by the
+// keyset columns. An empty cursor returns the first page.
+func (q *ListUsersQuery) EvalPage(ctx context.Context, cursor string, limit int) (ListUsersPage, error) {
+	if limit <= 0 {
+		return ListUsersPage{}, fmt.Errorf("ListUsers: limit must be positive, got %d", limit)
+	}
+	page, err := newListUsersPageQuery(q, cursor, limit)
+	if err != nil {
+		return ListUsersPage{}, err
+	}
+	q.Results = nil
+	if err := q.ex.Execute(ctx, page); err != nil {
+		return ListUsersPage{}, err
+	}
+	result := ListUsersPage{Items: q.Results}
+	if len(result.Items) > limit {
+		last := result.Items[limit-1]
+		result.Items, result.HasMore = result.Items[:limit], true
+		next, err := EncodeListUsersCursor(last.CreatedAt, last.ID)
+		if err != nil {
+			return ListUsersPage{}, err
+		}
+		result.NextCursor = next
+	}
+	return result, nil
+}
 func ExpectListUsers(results []User, err error) Step {
 	return Step{
 		SQL:  listUsers,
 		Args: nil,
 		Apply: func(q Query) error {
-			q.(*ListUsersQuery).SetResults(results)
+			q.(interface{ SetResults([]User) }).SetResults(results)
+			return err
+		},
+	}
+}
+
+// ExpectListUsersPage expects EvalPage to read the page following
+// cursor. results are the rows read from the database, up to limit+1 of them:
+// the extra row makes EvalPage report HasMore. It panics when cursor is
+// invalid.
+func ExpectListUsersPage(cursor string, limit int, results []User, err error) Step {
+	page, cursorErr := newListUsersPageQuery(&ListUsersQuery{}, cursor, limit)
+	if cursorErr != nil {
+		panic(cursorErr)
+	}
+	return Step{
+		SQL:  page.SQL(),
+		Args: page.Args(),
+		Apply: func(q Query) error {
+			q.(interface{ SetResults([]User) }).SetResults(results)
 			return err
 		},
 	}
@@ -552,6 +1016,18 @@ func (q *UpdateUserEmailQuery) SQL() string {
 	return updateUserEmail
 }
 
+func (q *UpdateUserEmailQuery) QueryName() string {
+	return "UpdateUserEmail"
+}
+
+func (q *UpdateUserEmailQuery) QueryCmd() string {
+	return ":execrows"
+}
+
+func (q *UpdateUserEmailQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *UpdateUserEmailQuery) Args() []any {
 	return []any{q.iD, q.email}
 }
@@ -569,6 +1045,13 @@ func (q *UpdateUserEmailQuery) Eval(ctx context.Context, iD int64, email string)
 	return q.RowsAffected, nil
 }
 
+// With sets the arguments of the query without executing it, e.g. to queue it in a Batch
+func (q *UpdateUserEmailQuery) With(iD int64, email string) *UpdateUserEmailQuery {
+	q.iD = iD
+	q.email = email
+	return q
+}
+
 func NewUpdateUserEmailQuery(ex QueryExecutor) *UpdateUserEmailQuery {
 	return &UpdateUserEmailQuery{ex: ex}
 }
@@ -582,3 +1065,14 @@ func ExpectUpdateUserEmail(iD int64, email string, rowsAffected int64, err error
 		},
 	}
 }
+
+func ExpectUpdateUserEmailMatching(iD, email ArgMatcher, rowsAffected int64, err error) Step {
+	return Step{
+		SQL:  updateUserEmail,
+		Args: []any{iD, email},
+		Apply: func(q Query) error {
+			q.(*UpdateUserEmailQuery).SetRowsAffected(rowsAffected)
+			return err
+		},
+	}
+}
@@ -2,9 +2,11 @@ package db_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/sqlc-dev/sqlc-gen-go/examples/pgx/db"
 	"github.com/testcontainers/testcontainers-go"
@@ -12,6 +14,24 @@ import (
 	"github.com/testcontainers/testcontainers-go/wait"
 )
 
+// recordingTracer records the name and row count of every traced query
+type recordingTracer struct {
+	started []string
+	rows    map[string]int64
+}
+
+func (r *recordingTracer) TraceQueryStart(ctx context.Context, query db.Query) context.Context {
+	r.started = append(r.started, query.QueryName())
+	return ctx
+}
+
+func (r *recordingTracer) TraceQueryEnd(ctx context.Context, query db.Query, err error, rowsAffected int64) {
+	if r.rows == nil {
+		r.rows = map[string]int64{}
+	}
+	r.rows[query.QueryName()] = rowsAffected
+}
+
 func setupTestDB(t *testing.T) (*pgxpool.Pool, func()) {
 	t.Helper()
 
@@ -152,6 +172,36 @@ func TestQueries(t *testing.T) {
 		}
 	})
 
+	// Test ListUsers pages (-- @paginate keyset(created_at, id))
+	t.Run("ListUsersPages", func(t *testing.T) {
+		users, err := db.NewListUsersQuery(executor).Eval(ctx)
+		if err != nil {
+			t.Fatalf("ListUsers failed: %v", err)
+		}
+
+		seen := map[int64]bool{}
+		cursor := ""
+		for {
+			page, err := db.NewListUsersQuery(executor).EvalPage(ctx, cursor, 1)
+			if err != nil {
+				t.Fatalf("EvalPage failed: %v", err)
+			}
+			for _, user := range page.Items {
+				if seen[user.ID] {
+					t.Fatalf("user %d returned twice", user.ID)
+				}
+				seen[user.ID] = true
+			}
+			if !page.HasMore {
+				break
+			}
+			cursor = page.NextCursor
+		}
+		if len(seen) != len(users) {
+			t.Errorf("expected %d users, got %d", len(users), len(seen))
+		}
+	})
+
 	// Test UpdateUserEmail (:execrows)
 	t.Run("UpdateUserEmail", func(t *testing.T) {
 		createQuery := db.NewCreateUserQuery(executor)
@@ -183,6 +233,67 @@ func TestQueries(t *testing.T) {
 		}
 	})
 
+	// Test Batch (typed queries queued in a single round trip)
+	t.Run("Batch", func(t *testing.T) {
+		created, err := db.NewCreateUserQuery(executor).Eval(ctx, db.CreateUserParams{
+			Name:   "batched",
+			Email:  "batched@example.com",
+			Status: db.UserStatusActive,
+		})
+		if err != nil {
+			t.Fatalf("CreateUser failed: %v", err)
+		}
+
+		update := db.NewUpdateUserEmailQuery(executor).With(created.ID, "batched.updated@example.com")
+		get := db.NewGetUserQuery(executor).With(created.ID)
+		list := db.NewListUsersQuery(executor)
+		del := db.NewDeleteUserQuery(executor).With(created.ID)
+		if err := executor.Execute(ctx, db.NewBatch(update, get, list, del)); err != nil {
+			t.Fatalf("batch failed: %v", err)
+		}
+		if update.RowsAffected != 1 {
+			t.Errorf("expected 1 row updated, got %d", update.RowsAffected)
+		}
+		if get.Result.Email != "batched.updated@example.com" {
+			t.Errorf("expected the updated email, got %s", get.Result.Email)
+		}
+		if len(list.Results) == 0 {
+			t.Error("expected ListUsers results")
+		}
+		if del.RowsAffected != 1 {
+			t.Errorf("expected 1 row deleted, got %d", del.RowsAffected)
+		}
+	})
+
+	// Test Pipeline (per-query errors, the other queries still run)
+	t.Run("Pipeline", func(t *testing.T) {
+		created, err := db.NewCreateUserQuery(executor).Eval(ctx, db.CreateUserParams{
+			Name:   "pipelined",
+			Email:  "pipelined@example.com",
+			Status: db.UserStatusActive,
+		})
+		if err != nil {
+			t.Fatalf("CreateUser failed: %v", err)
+		}
+
+		get := db.NewGetUserQuery(executor).With(created.ID)
+		missing := db.NewGetUserQuery(executor).With(-1)
+		count := db.NewCountUsersQuery(executor)
+		del := db.NewDeleteUserQuery(executor).With(created.ID)
+		err = executor.Pipeline(ctx, get, missing, count, del)
+
+		var pipelineErr *db.PipelineError
+		if !errors.As(err, &pipelineErr) {
+			t.Fatalf("expected a *db.PipelineError, got %v", err)
+		}
+		if !errors.Is(pipelineErr.Errs[1], pgx.ErrNoRows) {
+			t.Errorf("expected pgx.ErrNoRows for the missing user, got %v", pipelineErr.Errs[1])
+		}
+		if get.Result.ID != created.ID || count.Result == 0 || del.RowsAffected != 1 {
+			t.Errorf("unexpected results: %+v %d %d", get.Result, count.Result, del.RowsAffected)
+		}
+	})
+
 	// Test GetUserForUpdate (:one with FOR UPDATE)
 	t.Run("GetUserForUpdate", func(t *testing.T) {
 		createQuery := db.NewCreateUserQuery(executor)
@@ -516,4 +627,107 @@ func TestQueries(t *testing.T) {
 			}
 		}
 	})
+
+	// Middleware wraps every Execute call, including those made inside WithTx
+	t.Run("Middleware", func(t *testing.T) {
+		var calls []string
+		record := func(next db.ExecuteFunc) db.ExecuteFunc {
+			return func(ctx context.Context, q db.Query) error {
+				calls = append(calls, q.SQL())
+				return next(ctx, q)
+			}
+		}
+		mwExecutor := db.NewExecutor(pool, db.WithMiddleware(record))
+
+		if _, err := db.NewCountUsersQuery(mwExecutor).Eval(ctx); err != nil {
+			t.Fatalf("CountUsers failed: %v", err)
+		}
+		err := mwExecutor.WithTx(ctx, func(txExecutor db.QueryExecutor) error {
+			_, err := db.NewCountUsersQuery(txExecutor).Eval(ctx)
+			return err
+		})
+		if err != nil {
+			t.Fatalf("WithTx failed: %v", err)
+		}
+		if len(calls) != 2 {
+			t.Errorf("expected 2 middleware calls, got %d", len(calls))
+		}
+	})
+
+	// Iter streams rows one at a time and stops scanning when the loop breaks
+	t.Run("Iter", func(t *testing.T) {
+		var count int
+		for user, err := range db.NewListUsersQuery(executor).Iter(ctx) {
+			if err != nil {
+				t.Fatalf("ListUsers iteration failed: %v", err)
+			}
+			if user.ID == 0 {
+				t.Errorf("user not hydrated: %+v", user)
+			}
+			count++
+			if count == 2 {
+				break
+			}
+		}
+		if count != 2 {
+			t.Errorf("expected to stop after 2 users, got %d", count)
+		}
+
+		// The connection must be usable again after breaking out early
+		if _, err := db.NewCountUsersQuery(executor).Eval(ctx); err != nil {
+			t.Fatalf("CountUsers after Iter failed: %v", err)
+		}
+	})
+
+	// WithTxOptions starts the transaction with the requested isolation and access mode
+	t.Run("WithTxOptions", func(t *testing.T) {
+		err := executor.WithTxOptions(ctx, db.TxOptions{IsoLevel: pgx.Serializable, AccessMode: pgx.ReadOnly}, func(txExecutor db.QueryExecutor) error {
+			if _, err := db.NewCountUsersQuery(txExecutor).Eval(ctx); err != nil {
+				t.Errorf("CountUsers failed: %v", err)
+				return err
+			}
+			_, err := db.NewCreateUserQuery(txExecutor).Eval(ctx, db.CreateUserParams{
+				Name:   "readonly",
+				Email:  "readonly@example.com",
+				Status: db.UserStatusActive,
+			})
+			return err
+		})
+		if err == nil {
+			t.Fatal("expected write in read-only transaction to fail")
+		}
+	})
+
+	// QueryTracer sees copyfrom row counts and batch sizes, also inside WithTx
+	t.Run("Tracer", func(t *testing.T) {
+		tracer := &recordingTracer{}
+		traced := db.NewExecutor(pool, db.WithTracer(tracer))
+
+		err := traced.WithTx(ctx, func(txExecutor db.QueryExecutor) error {
+			if _, err := db.NewBulkInsertUsersQuery(txExecutor).Eval(ctx, []db.BulkInsertUsersParams{
+				{Name: "traced1", Email: "traced1@example.com"},
+				{Name: "traced2", Email: "traced2@example.com"},
+			}); err != nil {
+				return err
+			}
+			results, err := db.NewBatchGetUsersQuery(txExecutor).Eval(ctx, []int64{1, 2, 3})
+			if err != nil {
+				return err
+			}
+			results.Close()
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("WithTx failed: %v", err)
+		}
+		if len(tracer.started) != 2 {
+			t.Errorf("expected 2 traced queries, got %v", tracer.started)
+		}
+		if n := tracer.rows["BulkInsertUsers"]; n != 2 {
+			t.Errorf("expected 2 copied rows, got %d", n)
+		}
+		if n := tracer.rows["BatchGetUsers"]; n != 3 {
+			t.Errorf("expected batch size 3, got %d", n)
+		}
+	})
 }
@@ -5,10 +5,23 @@
 package db
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type DBTX interface {
@@ -27,6 +40,12 @@ type DBTXWithTx interface {
 type Query interface {
 	SQL() string
 	Args() []any
+	// QueryName returns the name from the sqlc "-- name:" annotation
+	QueryName() string
+	// QueryCmd returns the sqlc command, e.g. ":one" or ":many"
+	QueryCmd() string
+	// SourceFile returns the query file the query was declared in
+	SourceFile() string
 }
 
 type QueryOne interface {
@@ -39,6 +58,13 @@ type QueryMany interface {
 	ScanRow(row *sql.Rows) error
 }
 
+// QueryStream is a :many query that consumes rows one at a time
+type QueryStream interface {
+	Query
+	// ScanNext scans the current row and reports whether to keep reading
+	ScanNext(row *sql.Rows) (bool, error)
+}
+
 type QueryExec interface {
 	Query
 	SetRowsAffected(int64)
@@ -50,39 +76,249 @@ type QueryExecLastID interface {
 	SetRowsAffected(int64)
 }
 
+// QueryWithTimeout is implemented by queries annotated with a "-- @timeout"
+// comment. Executor.Execute runs them with a context bounded by Timeout.
+type QueryWithTimeout interface {
+	Query
+	Timeout() time.Duration
+}
+
 // QueryExecutor executes queries
 type QueryExecutor interface {
 	Execute(ctx context.Context, query Query) error
 	WithTx(ctx context.Context, fn func(QueryExecutor) error) error
+	WithTxOptions(ctx context.Context, opts TxOptions, fn func(QueryExecutor) error) error
+}
+
+// TxOptions configures transactions started by WithTxOptions
+type TxOptions = sql.TxOptions
+
+// ExecuteFunc executes a single query
+type ExecuteFunc func(ctx context.Context, query Query) error
+
+// ExecutorMiddleware wraps an ExecuteFunc to add behavior around every Execute call
+type ExecutorMiddleware func(next ExecuteFunc) ExecuteFunc
+
+// ExecutorOption configures an Executor
+type ExecutorOption func(*Executor)
+
+// WithMiddleware adds middlewares to the Executor. The first middleware is the
+// outermost one. Middlewares are also applied to executors created by WithTx.
+func WithMiddleware(mw ...ExecutorMiddleware) ExecutorOption {
+	return func(e *Executor) {
+		e.middlewares = append(e.middlewares, mw...)
+	}
+}
+
+// RetryPolicy controls how WithTx retries transactions that fail with an
+// error reported by IsRetryable
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one
+	MaxAttempts int
+	// Backoff returns the delay before the given retry, starting at 1.
+	// A nil Backoff retries immediately.
+	Backoff func(retry int) time.Duration
+}
+
+// WithRetryPolicy makes WithTx and WithTxOptions re-run fn in a fresh
+// transaction when it fails with a retryable error. Nested transactions are
+// never retried on their own, the outermost transaction is retried instead.
+func WithRetryPolicy(policy RetryPolicy) ExecutorOption {
+	return func(e *Executor) {
+		e.retryPolicy = policy
+	}
+}
+
+// QueryTracer is notified around every query run by an Executor, including
+// queries run inside WithTx. It can be used to record spans or metrics without
+// the generated code depending on a tracing library.
+type QueryTracer interface {
+	// TraceQueryStart is called before the query is sent to the database. The
+	// returned context is used for the query and passed to TraceQueryEnd.
+	TraceQueryStart(ctx context.Context, query Query) context.Context
+	// TraceQueryEnd is called once the query has finished. rowsAffected is
+	// the number of rows returned, affected, copied or queued in a batch.
+	TraceQueryEnd(ctx context.Context, query Query, err error, rowsAffected int64)
+}
+
+// WithTracer sets the QueryTracer of the Executor
+func WithTracer(tracer QueryTracer) ExecutorOption {
+	return func(e *Executor) {
+		e.tracer = tracer
+	}
+}
+
+// WithSQLComments makes the Executor append a sqlcommenter style comment such
+// as /* file='query.sql',query='GetUser' */ to every statement it sends, so
+// the query can be identified in the database activity views and slow logs.
+// tags and tags added to the context with ContextWithSQLCommentTags are
+// included in the comment. The SQL returned by Query.SQL is not changed.
+func WithSQLComments(tags map[string]string) ExecutorOption {
+	return func(e *Executor) {
+		e.sqlComments = true
+		e.sqlCommentTags = tags
+	}
+}
+
+type sqlCommentTagsKey struct{}
+
+// ContextWithSQLCommentTags returns a copy of ctx carrying tags, which are
+// added to the comment of queries run by an Executor created with
+// WithSQLComments. Tags already in ctx are kept unless overwritten.
+func ContextWithSQLCommentTags(ctx context.Context, tags map[string]string) context.Context {
+	merged := make(map[string]string, len(tags))
+	if parent, ok := ctx.Value(sqlCommentTagsKey{}).(map[string]string); ok {
+		for k, v := range parent {
+			merged[k] = v
+		}
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, sqlCommentTagsKey{}, merged)
+}
+
+// WithPreparedStatements makes the Executor prepare every statement on first
+// use and reuse the *sql.Stmt for later calls. Executors created by WithTx
+// rebind the statements to the transaction. Queries whose SQL text changes
+// from call to call, those using sqlc.slice and those run with
+// ContextWithSQLCommentTags tags, are not prepared so that the number of
+// statements stays bounded. Call Close to release the statements.
+func WithPreparedStatements() ExecutorOption {
+	return func(e *Executor) {
+		e.stmts = &stmtCache{db: e.db, stmts: map[string]*sql.Stmt{}}
+	}
+}
+
+// stmtCache holds the statements prepared by an Executor and its copies
+type stmtCache struct {
+	db    DBTX
+	mu    sync.Mutex
+	stmts map[string]*sql.Stmt
+}
+
+// get returns the statement cached under key, preparing query on first use
+func (c *stmtCache) get(ctx context.Context, key, query string) (*sql.Stmt, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if stmt, ok := c.stmts[key]; ok {
+		return stmt, nil
+	}
+	stmt, err := c.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	c.stmts[key] = stmt
+	return stmt, nil
+}
+
+func (c *stmtCache) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var errs []error
+	for query, stmt := range c.stmts {
+		if err := stmt.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		delete(c.stmts, query)
+	}
+	return errors.Join(errs...)
 }
 
 // Executor implements QueryExecutor using DBTX
 type Executor struct {
-	db       DBTX
-	dbWithTx DBTXWithTx
+	db             DBTX
+	dbWithTx       DBTXWithTx
+	middlewares    []ExecutorMiddleware
+	handler        ExecuteFunc
+	retryPolicy    RetryPolicy
+	tracer         QueryTracer
+	sqlComments    bool
+	sqlCommentTags map[string]string
+	savepoint      int
+	stmts          *stmtCache
+	tx             *sql.Tx
+	txStmts        map[string]*sql.Stmt
 }
 
 // NewExecutor creates a new Executor
-func NewExecutor(db DBTX) *Executor {
+func NewExecutor(db DBTX, opts ...ExecutorOption) *Executor {
 	dbWithTx, _ := db.(DBTXWithTx)
-	return &Executor{
+	e := &Executor{
 		db:       db,
 		dbWithTx: dbWithTx,
 	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	e.buildHandler()
+	return e
+}
+
+// withDB returns a copy of the Executor with the same options bound to db
+func (e *Executor) withDB(db DBTX) *Executor {
+	executor := *e
+	executor.db = db
+	executor.retryPolicy = RetryPolicy{}
+	executor.dbWithTx, _ = db.(DBTXWithTx)
+	executor.buildHandler()
+	return &executor
+}
+
+// ForTx returns an Executor with the same options that runs queries within
+// tx, a transaction started outside of WithTx
+func (e *Executor) ForTx(tx DBTX) *Executor {
+	return e.withDB(tx)
+}
+
+func (e *Executor) buildHandler() {
+	handler := e.execute
+	for i := len(e.middlewares) - 1; i >= 0; i-- {
+		handler = e.middlewares[i](handler)
+	}
+	e.handler = handler
 }
 
-// WithTx executes a function within a transaction
+// WithTx executes a function within a transaction. Calling WithTx on the
+// executor passed to fn, or on an Executor created for a *sql.Tx, runs the
+// nested function within a savepoint instead.
 func (e *Executor) WithTx(ctx context.Context, fn func(QueryExecutor) error) error {
+	return e.WithTxOptions(ctx, TxOptions{}, fn)
+}
+
+// WithTxOptions executes a function within a transaction started with opts
+func (e *Executor) WithTxOptions(ctx context.Context, opts TxOptions, fn func(QueryExecutor) error) error {
+	for retry := 1; ; retry++ {
+		err := e.runTx(ctx, opts, fn)
+		if err == nil || retry >= e.retryPolicy.MaxAttempts || !IsRetryable(err) {
+			return err
+		}
+		if e.retryPolicy.Backoff != nil {
+			if err := sleepContext(ctx, e.retryPolicy.Backoff(retry)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (e *Executor) runTx(ctx context.Context, opts TxOptions, fn func(QueryExecutor) error) error {
+	if _, ok := e.db.(*sql.Tx); ok {
+		return e.runSavepoint(ctx, opts, fn)
+	}
 	if e.dbWithTx == nil {
 		return fmt.Errorf("database does not support transactions")
 	}
 
-	tx, err := e.dbWithTx.BeginTx(ctx, nil)
+	tx, err := e.dbWithTx.BeginTx(ctx, &opts)
 	if err != nil {
 		return err
 	}
 
-	executor := NewExecutor(tx)
+	executor := e.withDB(tx)
+	if e.stmts != nil {
+		executor.tx = tx
+		executor.txStmts = map[string]*sql.Stmt{}
+	}
 
 	if err := fn(executor); err != nil {
 		if rbErr := tx.Rollback(); rbErr != nil {
@@ -94,126 +330,1580 @@ func (e *Executor) WithTx(ctx context.Context, fn func(QueryExecutor) error) err
 	return tx.Commit()
 }
 
+func (e *Executor) runSavepoint(ctx context.Context, opts TxOptions, fn func(QueryExecutor) error) error {
+	if opts != (TxOptions{}) {
+		return fmt.Errorf("transaction options are not supported for nested transactions")
+	}
+
+	executor := e.withDB(e.db)
+	executor.savepoint = e.savepoint + 1
+	name := fmt.Sprintf("sp_%d", executor.savepoint)
+
+	if _, err := e.db.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+
+	if err := fn(executor); err != nil {
+		if _, rbErr := e.db.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rbErr != nil {
+			return fmt.Errorf("tx failed: %w, rollback failed: %v", err, rbErr)
+		}
+		return err
+	}
+
+	_, err := e.db.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
+// IsRetryable reports whether err is a serialization failure (40001) or a
+// deadlock (40P01) that may succeed when the transaction is retried
+func IsRetryable(err error) bool {
+	var stateErr interface{ SQLState() string }
+	if errors.As(err, &stateErr) {
+		code := stateErr.SQLState()
+		return code == "40001" || code == "40P01"
+	}
+	return false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Execute runs the query through the middleware chain. Queries implementing
+// QueryWithTimeout run with a context that is canceled after their timeout.
 func (e *Executor) Execute(ctx context.Context, query Query) error {
+	if q, ok := query.(QueryWithTimeout); ok {
+		if timeout := q.Timeout(); timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+	}
+	return e.handler(ctx, query)
+}
+
+func (e *Executor) execute(ctx context.Context, query Query) error {
+	if e.tracer == nil {
+		n, err := e.run(ctx, query)
+		setRowsAffected(ctx, n)
+		return err
+	}
+	ctx = e.tracer.TraceQueryStart(ctx, query)
+	n, err := e.run(ctx, query)
+	e.tracer.TraceQueryEnd(ctx, query, err, n)
+	setRowsAffected(ctx, n)
+	return err
+}
+
+// Prepare creates an Executor with WithPreparedStatements and prepares every
+// generated query up front, so that invalid queries are reported immediately
+func Prepare(ctx context.Context, db DBTX, opts ...ExecutorOption) (*Executor, error) {
+	e := NewExecutor(db, append(opts, WithPreparedStatements())...)
+	err := e.Prepare(ctx,
+		&CountUsersQuery{},
+		&CreatePostQuery{},
+		&CreateUserQuery{},
+		&CreateUserGetIDQuery{},
+		&DeleteUserQuery{},
+		&GetPostWithAuthorQuery{},
+		&GetUserQuery{},
+		&ListPostsWithAuthorQuery{},
+		&ListUsersQuery{},
+		&ListUsersByIDQuery{},
+		&UpdateUserEmailQuery{},
+		&UpdateUserNameQuery{},
+	)
+	if err != nil {
+		e.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// Prepare prepares the statements of queries up front. The Executor must
+// have been created with WithPreparedStatements.
+func (e *Executor) Prepare(ctx context.Context, queries ...Query) error {
+	if e.stmts == nil {
+		return fmt.Errorf("executor was created without WithPreparedStatements")
+	}
+	for _, q := range queries {
+		if !e.prepared(ctx, q) {
+			continue
+		}
+		if _, err := e.stmts.get(ctx, q.SQL(), e.sql(ctx, q)); err != nil {
+			return fmt.Errorf("prepare %s: %w", q.QueryName(), err)
+		}
+	}
+	return nil
+}
+
+// Close closes the prepared statements of the Executor and of the executors
+// created by WithTx. Statements are prepared again if the Executor is used
+// after Close.
+func (e *Executor) Close() error {
+	if e.stmts == nil {
+		return nil
+	}
+	return e.stmts.close()
+}
+
+// QueryWithSqlcSlices is implemented by queries using sqlc.slice, whose SQL
+// text depends on the length of their slice arguments
+type QueryWithSqlcSlices interface {
+	Query
+	HasSqlcSlices() bool
+}
+
+// prepared reports whether query runs with a prepared statement. Statements
+// are cached by the constant SQL of the query, so queries whose SQL text
+// changes from call to call run unprepared.
+func (e *Executor) prepared(ctx context.Context, query Query) bool {
+	if q, ok := query.(QueryWithSqlcSlices); ok && q.HasSqlcSlices() {
+		return false
+	}
+	if e.sqlComments {
+		if _, ok := ctx.Value(sqlCommentTagsKey{}).(map[string]string); ok {
+			return false
+		}
+	}
+	return true
+}
+
+// stmt returns the prepared statement for query, bound to the transaction of
+// the Executor if there is one
+func (e *Executor) stmt(ctx context.Context, query Query) (*sql.Stmt, error) {
+	key := query.SQL()
+	stmt, err := e.stmts.get(ctx, key, e.sql(ctx, query))
+	if err != nil || e.tx == nil {
+		return stmt, err
+	}
+	if txStmt, ok := e.txStmts[key]; ok {
+		return txStmt, nil
+	}
+	txStmt := e.tx.StmtContext(ctx, stmt)
+	e.txStmts[key] = txStmt
+	return txStmt, nil
+}
+
+// stmtDB is a DBTX that runs the query being executed with its prepared
+// statement
+type stmtDB struct {
+	DBTX
+	stmt *sql.Stmt
+}
+
+func (s stmtDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return s.stmt.ExecContext(ctx, args...)
+}
+
+func (s stmtDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return s.stmt.QueryContext(ctx, args...)
+}
+
+func (s stmtDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return s.stmt.QueryRowContext(ctx, args...)
+}
+
+// sql returns the SQL sent for query, with the sqlcommenter comment appended
+// when WithSQLComments is used
+func (e *Executor) sql(ctx context.Context, query Query) string {
+	if !e.sqlComments {
+		return query.SQL()
+	}
+	tags := map[string]string{
+		"query": query.QueryName(),
+		"file":  query.SourceFile(),
+	}
+	for k, v := range e.sqlCommentTags {
+		tags[k] = v
+	}
+	if ctxTags, ok := ctx.Value(sqlCommentTagsKey{}).(map[string]string); ok {
+		for k, v := range ctxTags {
+			tags[k] = v
+		}
+	}
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(query.SQL(), " \t\n;"))
+	b.WriteString(" /*")
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(sqlCommentEscape(k))
+		b.WriteString("='")
+		b.WriteString(sqlCommentEscape(tags[k]))
+		b.WriteString("'")
+	}
+	b.WriteString("*/")
+	return b.String()
+}
+
+// sqlCommentEscape URL-encodes s as required by the sqlcommenter spec, which
+// also keeps quotes and comment terminators out of the comment
+func sqlCommentEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// run executes the query and returns the number of rows it returned or affected
+func (e *Executor) run(ctx context.Context, query Query) (int64, error) {
+	db := e.db
+	if e.stmts != nil && e.prepared(ctx, query) {
+		stmt, err := e.stmt(ctx, query)
+		if err != nil {
+			return 0, fmt.Errorf("prepare %s: %w", query.QueryName(), err)
+		}
+		db = stmtDB{e.db, stmt}
+	}
 	switch q := query.(type) {
 	case QueryOne:
-		row := e.db.QueryRowContext(ctx, q.SQL(), q.Args()...)
-		return q.Scan(row)
+		row := db.QueryRowContext(ctx, e.sql(ctx, q), q.Args()...)
+		if err := q.Scan(row); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	case QueryStream:
+		rows, err := db.QueryContext(ctx, e.sql(ctx, q), q.Args()...)
+		if err != nil {
+			return 0, err
+		}
+		defer rows.Close()
+		var n int64
+		for rows.Next() {
+			more, err := q.ScanNext(rows)
+			if err != nil {
+				return n, err
+			}
+			n++
+			if !more {
+				return n, nil
+			}
+		}
+		if err := rows.Close(); err != nil {
+			return n, err
+		}
+		return n, rows.Err()
 	case QueryMany:
-		rows, err := e.db.QueryContext(ctx, q.SQL(), q.Args()...)
+		rows, err := db.QueryContext(ctx, e.sql(ctx, q), q.Args()...)
 		if err != nil {
-			return err
+			return 0, err
 		}
 		defer rows.Close()
+		var n int64
 		for rows.Next() {
 			if err := q.ScanRow(rows); err != nil {
-				return err
+				return n, err
 			}
+			n++
 		}
 		if err := rows.Close(); err != nil {
-			return err
+			return n, err
 		}
-		return rows.Err()
+		return n, rows.Err()
 	case QueryExecLastID:
-		result, err := e.db.ExecContext(ctx, q.SQL(), q.Args()...)
+		result, err := db.ExecContext(ctx, e.sql(ctx, q), q.Args()...)
 		if err != nil {
-			return err
+			return 0, err
 		}
 		lastID, err := result.LastInsertId()
 		if err != nil {
-			return err
+			return 0, err
 		}
 		q.SetLastInsertID(lastID)
 		n, err := result.RowsAffected()
 		if err != nil {
-			return err
+			return 0, err
 		}
 		q.SetRowsAffected(n)
-		return nil
+		return n, nil
 	case QueryExec:
-		result, err := e.db.ExecContext(ctx, q.SQL(), q.Args()...)
+		result, err := db.ExecContext(ctx, e.sql(ctx, q), q.Args()...)
 		if err != nil {
-			return err
+			return 0, err
 		}
 		n, err := result.RowsAffected()
 		if err != nil {
-			return err
+			return 0, err
 		}
 		q.SetRowsAffected(n)
-		return nil
+		return n, nil
 	default:
-		return nil
+		return 0, nil
+	}
+}
+
+// ExecuteAll runs independent read queries concurrently on ex and waits for
+// all of them. At most limit queries run at the same time, a limit of 0 or
+// less runs them all at once. The first failing query cancels the context of
+// the others and its error is returned. Queries other than QueryOne and
+// QueryMany, and the ones that must run on the primary (see PrimaryQuery) such
+// as INSERT ... RETURNING or SELECT ... FOR UPDATE, are rejected before any
+// query runs.
+//
+// The limit is deliberately a positional argument rather than an option: the
+// queries take the variadic parameter, and the limit is a property of the
+// call rather than of ex.
+//
+// ExecuteAll is meant for an executor backed by a pool of connections. It must
+// not be used inside WithTx: a transaction is bound to a single connection,
+// which cannot run queries concurrently.
+func ExecuteAll(ctx context.Context, ex QueryExecutor, limit int, queries ...Query) error {
+	for _, q := range queries {
+		switch q.(type) {
+		case QueryOne, QueryMany:
+		default:
+			return fmt.Errorf("%s %s queries cannot be run by ExecuteAll, only :one and :many queries can", q.QueryName(), q.QueryCmd())
+		}
+		if p, ok := q.(PrimaryQuery); ok && p.UsesPrimary() {
+			return fmt.Errorf("%s must run on the primary and cannot be run by ExecuteAll", q.QueryName())
+		}
+	}
+	if limit <= 0 {
+		limit = len(queries)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	sem := make(chan struct{}, limit)
+	for _, q := range queries {
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := ex.Execute(ctx, q); err != nil {
+				once.Do(func() {
+					firstErr = fmt.Errorf("%s: %w", q.QueryName(), err)
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// PrimaryQuery is implemented by :one and :many queries that must run on the
+// primary, such as SELECT ... FOR UPDATE, INSERT ... RETURNING or queries
+// annotated with a "-- @primary" comment
+type PrimaryQuery interface {
+	Query
+	UsesPrimary() bool
+}
+
+// RoutingExecutor implements QueryExecutor on top of a primary database and
+// its read replicas. QueryOne and QueryMany queries run on the replicas in
+// round-robin order, everything else, including transactions, runs on the
+// primary.
+type RoutingExecutor struct {
+	primary  *Executor
+	replicas []*Executor
+	next     atomic.Uint64
+}
+
+// NewRoutingExecutor creates a RoutingExecutor. opts are applied to the
+// executors of the primary and of every replica. Without replicas all queries
+// run on the primary.
+func NewRoutingExecutor(primary DBTX, replicas []DBTX, opts ...ExecutorOption) *RoutingExecutor {
+	r := &RoutingExecutor{primary: NewExecutor(primary, opts...)}
+	for _, db := range replicas {
+		r.replicas = append(r.replicas, NewExecutor(db, opts...))
+	}
+	return r
+}
+
+// Primary returns the Executor of the primary database
+func (r *RoutingExecutor) Primary() *Executor {
+	return r.primary
+}
+
+// ForTx returns the Executor of the primary bound to tx, a transaction started
+// outside of WithTx
+func (r *RoutingExecutor) ForTx(tx DBTX) *Executor {
+	return r.primary.ForTx(tx)
+}
+
+// Execute runs the query on a replica when it only reads data, otherwise on the primary
+func (r *RoutingExecutor) Execute(ctx context.Context, query Query) error {
+	return r.route(query).Execute(ctx, query)
+}
+
+func (r *RoutingExecutor) route(query Query) *Executor {
+	if len(r.replicas) == 0 {
+		return r.primary
+	}
+	if q, ok := query.(PrimaryQuery); ok && q.UsesPrimary() {
+		return r.primary
+	}
+	switch query.(type) {
+	case QueryOne, QueryMany:
+		n := r.next.Add(1) - 1
+		return r.replicas[n%uint64(len(r.replicas))]
+	default:
+		return r.primary
 	}
 }
 
+// WithTx executes a function within a transaction on the primary. Every query
+// executed by fn runs on the primary as well.
+func (r *RoutingExecutor) WithTx(ctx context.Context, fn func(QueryExecutor) error) error {
+	return r.primary.WithTx(ctx, fn)
+}
+
+// WithTxOptions executes a function within a transaction on the primary started with opts
+func (r *RoutingExecutor) WithTxOptions(ctx context.Context, opts TxOptions, fn func(QueryExecutor) error) error {
+	return r.primary.WithTxOptions(ctx, opts, fn)
+}
+
 // Compile-time interface checks
 var (
-	_ DBTX       = (*sql.DB)(nil)
-	_ DBTX       = (*sql.Conn)(nil)
-	_ DBTX       = (*sql.Tx)(nil)
-	_ DBTXWithTx = (*sql.DB)(nil)
-	_ DBTXWithTx = (*sql.Conn)(nil)
+	_ DBTX          = (*sql.DB)(nil)
+	_ DBTX          = (*sql.Conn)(nil)
+	_ DBTX          = (*sql.Tx)(nil)
+	_ DBTXWithTx    = (*sql.DB)(nil)
+	_ DBTXWithTx    = (*sql.Conn)(nil)
+	_ QueryExecutor = (*Executor)(nil)
+	_ QueryExecutor = (*RoutingExecutor)(nil)
 )
 
-type Step struct {
-	SQL   string
-	Args  []any
-	Apply func(Query) error
+// RedactedArg replaces the values of query arguments in LoggingExecutor
+// output, unless their column is listed in the log_args_allowlist option
+const RedactedArg = "[REDACTED]"
+
+// QueryWithLogArgs is implemented by queries with arguments whose columns are
+// listed in the log_args_allowlist option
+type QueryWithLogArgs interface {
+	Query
+	LogArgs() []any
 }
 
-// StubExecutor verifies queries are executed in expected order with expected arguments.
-type StubExecutor struct {
-	t interface {
-		Helper()
-		Errorf(format string, args ...any)
+// LoggingOption configures a LoggingExecutor
+type LoggingOption func(*LoggingExecutor)
+
+// WithSlowThreshold makes LoggingExecutor log queries that take d or longer at
+// warn level. Other successful queries are logged at debug level.
+func WithSlowThreshold(d time.Duration) LoggingOption {
+	return func(l *LoggingExecutor) {
+		l.slowThreshold = d
 	}
-	steps []Step
-	i     int
 }
 
-func NewStubExecutor(t interface {
-	Helper()
-	Errorf(format string, args ...any)
-}, steps ...Step) *StubExecutor {
-	return &StubExecutor{t: t, steps: steps}
+// LoggingExecutor wraps a QueryExecutor and logs every query with its name,
+// command, duration, rows affected, arguments and error using log/slog.
+// Failed queries are logged at error level.
+type LoggingExecutor struct {
+	next          QueryExecutor
+	logger        *slog.Logger
+	slowThreshold time.Duration
 }
 
-func (s *StubExecutor) Execute(ctx context.Context, q Query) error {
-	s.t.Helper()
+// NewLoggingExecutor creates a LoggingExecutor. A nil logger uses slog.Default().
+func NewLoggingExecutor(next QueryExecutor, logger *slog.Logger, opts ...LoggingOption) *LoggingExecutor {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	l := &LoggingExecutor{next: next, logger: logger}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Execute runs the query on the wrapped executor and logs it
+func (l *LoggingExecutor) Execute(ctx context.Context, query Query) error {
+	rows := int64(-1)
+	start := time.Now()
+	err := l.next.Execute(context.WithValue(ctx, rowsAffectedKey{}, &rows), query)
+	duration := time.Since(start)
 
-	if s.i >= len(s.steps) {
-		s.t.Errorf("unexpected query: SQL=%q Args=%v", q.SQL(), q.Args())
-		return fmt.Errorf("unexpected query")
+	level, msg := slog.LevelDebug, "query"
+	switch {
+	case err != nil:
+		level, msg = slog.LevelError, "query failed"
+	case l.slowThreshold > 0 && duration >= l.slowThreshold:
+		level, msg = slog.LevelWarn, "slow query"
+	}
+	if !l.logger.Enabled(ctx, level) {
+		return err
 	}
 
-	step := s.steps[s.i]
-	s.i++
+	attrs := []slog.Attr{
+		slog.String("query", query.QueryName()),
+		slog.String("cmd", query.QueryCmd()),
+		slog.Duration("duration", duration),
+		slog.Any("args", logArgs(query)),
+	}
+	// rows stays -1 when the wrapped executor is not an Executor
+	if rows >= 0 {
+		attrs = append(attrs, slog.Int64("rows_affected", rows))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	l.logger.LogAttrs(ctx, level, msg, attrs...)
+	return err
+}
 
-	if q.SQL() != step.SQL {
-		s.t.Errorf("SQL mismatch at step %d:\nwant: %q\ngot:  %q", s.i, step.SQL, q.SQL())
-		return fmt.Errorf("SQL mismatch")
+// WithTx executes a function within a transaction, logging the queries run by fn
+func (l *LoggingExecutor) WithTx(ctx context.Context, fn func(QueryExecutor) error) error {
+	return l.next.WithTx(ctx, l.wrap(fn))
+}
+
+// WithTxOptions executes a function within a transaction started with opts,
+// logging the queries run by fn
+func (l *LoggingExecutor) WithTxOptions(ctx context.Context, opts TxOptions, fn func(QueryExecutor) error) error {
+	return l.next.WithTxOptions(ctx, opts, l.wrap(fn))
+}
+
+func (l *LoggingExecutor) wrap(fn func(QueryExecutor) error) func(QueryExecutor) error {
+	return func(tx QueryExecutor) error {
+		executor := *l
+		executor.next = tx
+		return fn(&executor)
 	}
+}
 
-	if !reflect.DeepEqual(q.Args(), step.Args) {
-		s.t.Errorf("Args mismatch at step %d:\nwant: %v\ngot:  %v", s.i, step.Args, q.Args())
-		return fmt.Errorf("args mismatch")
+func logArgs(query Query) []any {
+	if q, ok := query.(QueryWithLogArgs); ok {
+		return q.LogArgs()
+	}
+	args := make([]any, len(query.Args()))
+	for i := range args {
+		args[i] = RedactedArg
 	}
+	return args
+}
 
-	if step.Apply != nil {
-		return step.Apply(q)
+type rowsAffectedKey struct{}
+
+// setRowsAffected reports n to the LoggingExecutor that started the query
+func setRowsAffected(ctx context.Context, n int64) {
+	if rows, ok := ctx.Value(rowsAffectedKey{}).(*int64); ok {
+		*rows = n
 	}
-	return nil
 }
 
-func (s *StubExecutor) AssertDone() {
-	s.t.Helper()
-	if s.i != len(s.steps) {
-		s.t.Errorf("not all steps executed: %d/%d", s.i, len(s.steps))
+var _ QueryExecutor = (*LoggingExecutor)(nil)
+
+// FixtureEntry is a query recorded by RecordingExecutor and served back by
+// ReplayExecutor
+type FixtureEntry struct {
+	Name         string           `json:"name"`
+	SQL          string           `json:"sql"`
+	Args         json.RawMessage  `json:"args"`
+	Columns      []string         `json:"columns,omitempty"`
+	Rows         [][]FixtureValue `json:"rows,omitempty"`
+	RowsAffected int64            `json:"rows_affected,omitempty"`
+	LastInsertID int64            `json:"last_insert_id,omitempty"`
+	Error        string           `json:"error,omitempty"`
+
+	// err is the original error while recording
+	err error
+}
+
+// FixtureValue is a value returned by the driver. It is encoded as an object
+// naming its type, e.g. {"int64":1}, so that it is replayed with the same type.
+type FixtureValue struct {
+	Value driver.Value
+}
+
+type fixtureValueJSON struct {
+	Int64   *int64     `json:"int64,omitempty"`
+	Float64 *float64   `json:"float64,omitempty"`
+	Bool    *bool      `json:"bool,omitempty"`
+	String  *string    `json:"string,omitempty"`
+	Bytes   *[]byte    `json:"bytes,omitempty"`
+	Time    *time.Time `json:"time,omitempty"`
+}
+
+func (v FixtureValue) MarshalJSON() ([]byte, error) {
+	var out fixtureValueJSON
+	switch x := v.Value.(type) {
+	case nil:
+		return []byte("null"), nil
+	case int64:
+		out.Int64 = &x
+	case float64:
+		out.Float64 = &x
+	case bool:
+		out.Bool = &x
+	case string:
+		out.String = &x
+	case []byte:
+		if x == nil {
+			return []byte("null"), nil
+		}
+		return json.Marshal(map[string][]byte{"bytes": x})
+	case time.Time:
+		out.Time = &x
+	default:
+		return nil, fmt.Errorf("unsupported fixture value of type %T", v.Value)
 	}
+	return json.Marshal(out)
 }
 
-func (s *StubExecutor) WithTx(ctx context.Context, fn func(QueryExecutor) error) error {
-	return fn(s)
+func (v *FixtureValue) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		v.Value = nil
+		return nil
+	}
+	var in fixtureValueJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	switch {
+	case in.Int64 != nil:
+		v.Value = *in.Int64
+	case in.Float64 != nil:
+		v.Value = *in.Float64
+	case in.Bool != nil:
+		v.Value = *in.Bool
+	case in.String != nil:
+		v.Value = *in.String
+	case in.Bytes != nil:
+		v.Value = append([]byte{}, *in.Bytes...)
+	case in.Time != nil:
+		v.Value = *in.Time
+	default:
+		return fmt.Errorf("invalid fixture value %s", data)
+	}
+	return nil
+}
+
+// ReadFixture reads entries written by RecordingExecutor.WriteFixture
+func ReadFixture(path string) ([]FixtureEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []FixtureEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("reading fixture %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+type fixtureLog struct {
+	mu      sync.Mutex
+	entries []FixtureEntry
+}
+
+// RecordingExecutor runs queries with an Executor and records their name, SQL,
+// arguments and results, so that they can be written to a fixture file and
+// served by ReplayExecutor. Queries scan the recorded rows rather than the
+// rows of the database, so a recorded run behaves like its replay.
+type RecordingExecutor struct {
+	ex  *Executor
+	log *fixtureLog
+}
+
+// NewRecordingExecutor records the queries run by ex
+func NewRecordingExecutor(ex *Executor) *RecordingExecutor {
+	return &RecordingExecutor{ex: ex, log: &fixtureLog{}}
+}
+
+func (r *RecordingExecutor) Execute(ctx context.Context, query Query) error {
+	entry := &FixtureEntry{Name: query.QueryName()}
+	ex := r.ex.withDB(&recordingDB{DBTX: r.ex.db, entry: entry})
+	ex.stmts = nil
+	err := ex.Execute(ctx, query)
+
+	r.log.mu.Lock()
+	r.log.entries = append(r.log.entries, *entry)
+	r.log.mu.Unlock()
+	return err
+}
+
+func (r *RecordingExecutor) WithTx(ctx context.Context, fn func(QueryExecutor) error) error {
+	return r.WithTxOptions(ctx, TxOptions{}, fn)
+}
+
+// WithTxOptions runs fn in a transaction of the wrapped Executor and records
+// the queries run by fn
+func (r *RecordingExecutor) WithTxOptions(ctx context.Context, opts TxOptions, fn func(QueryExecutor) error) error {
+	return r.ex.WithTxOptions(ctx, opts, func(tx QueryExecutor) error {
+		return fn(&RecordingExecutor{ex: tx.(*Executor), log: r.log})
+	})
+}
+
+// Entries returns the queries recorded so far, in execution order
+func (r *RecordingExecutor) Entries() []FixtureEntry {
+	r.log.mu.Lock()
+	defer r.log.mu.Unlock()
+	return append([]FixtureEntry(nil), r.log.entries...)
+}
+
+// WriteFixture writes the recorded queries to path as JSON
+func (r *RecordingExecutor) WriteFixture(path string) error {
+	data, err := json.MarshalIndent(r.Entries(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// recordingDB runs queries against DBTX and records them into entry. Rows are
+// read in full and then served from entry.
+type recordingDB struct {
+	DBTX
+	entry *FixtureEntry
+}
+
+func (d *recordingDB) record(query string, args []any) error {
+	d.entry.SQL = query
+	if args == nil {
+		args = []any{}
+	}
+	data, err := json.Marshal(args)
+	if err != nil {
+		return fmt.Errorf("recording args of %s: %w", d.entry.Name, err)
+	}
+	d.entry.Args = data
+	return nil
+}
+
+func (d *recordingDB) fail(err error) error {
+	d.entry.Error = err.Error()
+	d.entry.err = err
+	return err
+}
+
+func (d *recordingDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if err := d.record(query, args); err != nil {
+		return nil, d.fail(err)
+	}
+	res, err := d.DBTX.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, d.fail(err)
+	}
+	d.entry.RowsAffected, _ = res.RowsAffected()
+	d.entry.LastInsertID, _ = res.LastInsertId()
+	return res, nil
+}
+
+func (d *recordingDB) recordRows(ctx context.Context, query string, args []any) {
+	if err := d.record(query, args); err != nil {
+		d.fail(err)
+		return
+	}
+	rows, err := d.DBTX.QueryContext(ctx, query, args...)
+	if err != nil {
+		d.fail(err)
+		return
+	}
+	defer rows.Close()
+	if d.entry.Columns, err = rows.Columns(); err != nil {
+		d.fail(err)
+		return
+	}
+	for rows.Next() {
+		values := make([]any, len(d.entry.Columns))
+		dest := make([]any, len(values))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			d.fail(err)
+			return
+		}
+		row := make([]FixtureValue, len(values))
+		for i, v := range values {
+			row[i] = FixtureValue{Value: v}
+		}
+		d.entry.Rows = append(d.entry.Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		d.fail(err)
+	}
+}
+
+func (d *recordingDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	d.recordRows(ctx, query, args)
+	return fixtureDB().QueryContext(context.WithValue(ctx, fixtureEntryKey{}, d.entry), query, args...)
+}
+
+func (d *recordingDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	d.recordRows(ctx, query, args)
+	return fixtureDB().QueryRowContext(context.WithValue(ctx, fixtureEntryKey{}, d.entry), query, args...)
+}
+
+// ReplayExecutor serves queries from fixture entries recorded by
+// RecordingExecutor. Queries must run in the recorded order with the recorded
+// SQL and arguments. The rows are scanned by the queries' own Scan methods.
+type ReplayExecutor struct {
+	ex      *Executor
+	mu      sync.Mutex
+	entries []FixtureEntry
+}
+
+// NewReplayExecutor replays entries. opts should match the options of the
+// recorded Executor, as options like WithSQLComments change the SQL sent.
+func NewReplayExecutor(entries []FixtureEntry, opts ...ExecutorOption) *ReplayExecutor {
+	return &ReplayExecutor{ex: NewExecutor(fixtureDB(), opts...), entries: entries}
+}
+
+func (r *ReplayExecutor) Execute(ctx context.Context, query Query) error {
+	r.mu.Lock()
+	if len(r.entries) == 0 {
+		r.mu.Unlock()
+		return fmt.Errorf("replay %s: no fixture entries left", query.QueryName())
+	}
+	entry := r.entries[0]
+	r.entries = r.entries[1:]
+	r.mu.Unlock()
+
+	if entry.Name != query.QueryName() {
+		return fmt.Errorf("replay %s: fixture expects query %s", query.QueryName(), entry.Name)
+	}
+	return r.ex.Execute(context.WithValue(ctx, fixtureEntryKey{}, &entry), query)
+}
+
+func (r *ReplayExecutor) WithTx(ctx context.Context, fn func(QueryExecutor) error) error {
+	return r.WithTxOptions(ctx, TxOptions{}, fn)
+}
+
+// WithTxOptions runs fn against the replay executor itself
+func (r *ReplayExecutor) WithTxOptions(ctx context.Context, opts TxOptions, fn func(QueryExecutor) error) error {
+	return fn(r)
+}
+
+// Remaining returns the number of entries not replayed yet
+func (r *ReplayExecutor) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.entries)
+}
+
+type fixtureEntryKey struct{}
+
+// fixtureDB serves the FixtureEntry stored in the context of each call
+var fixtureDB = sync.OnceValue(func() *sql.DB {
+	return sql.OpenDB(fixtureConnector{})
+})
+
+type fixtureConnector struct{}
+
+func (fixtureConnector) Connect(context.Context) (driver.Conn, error) { return fixtureConn{}, nil }
+func (fixtureConnector) Driver() driver.Driver                        { return fixtureDriver{} }
+
+type fixtureDriver struct{}
+
+func (fixtureDriver) Open(string) (driver.Conn, error) { return fixtureConn{}, nil }
+
+type fixtureConn struct{}
+
+func (fixtureConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("fixtures do not support prepared statements")
+}
+
+func (fixtureConn) Close() error { return nil }
+
+func (fixtureConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("fixtures do not support transactions")
+}
+
+// CheckNamedValue passes arguments through unchanged, so that they are
+// compared with the recorded ones as the query sent them
+func (fixtureConn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+func (fixtureConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	entry, err := fixtureEntryFor(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fixtureRows{columns: entry.Columns, rows: entry.Rows}, nil
+}
+
+func (fixtureConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	entry, err := fixtureEntryFor(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return fixtureResult{entry}, nil
+}
+
+func fixtureEntryFor(ctx context.Context, query string, args []driver.NamedValue) (*FixtureEntry, error) {
+	entry, ok := ctx.Value(fixtureEntryKey{}).(*FixtureEntry)
+	if !ok {
+		return nil, errors.New("no fixture entry for query")
+	}
+	if entry.err != nil {
+		return nil, entry.err
+	}
+	values := make([]any, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	got, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	var want bytes.Buffer
+	if err := json.Compact(&want, entry.Args); err != nil {
+		return nil, err
+	}
+	if query != entry.SQL || !bytes.Equal(got, want.Bytes()) {
+		return nil, fmt.Errorf("replay %s: query differs from fixture:\nwant: %q %s\ngot:  %q %s", entry.Name, entry.SQL, want.Bytes(), query, got)
+	}
+	if entry.Error != "" {
+		return nil, errors.New(entry.Error)
+	}
+	return entry, nil
+}
+
+type fixtureRows struct {
+	columns []string
+	rows    [][]FixtureValue
+}
+
+func (r *fixtureRows) Columns() []string { return r.columns }
+func (r *fixtureRows) Close() error      { return nil }
+
+func (r *fixtureRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	for i, v := range r.rows[0] {
+		dest[i] = v.Value
+	}
+	r.rows = r.rows[1:]
+	return nil
+}
+
+type fixtureResult struct {
+	entry *FixtureEntry
+}
+
+func (r fixtureResult) LastInsertId() (int64, error) { return r.entry.LastInsertID, nil }
+func (r fixtureResult) RowsAffected() (int64, error) { return r.entry.RowsAffected, nil }
+
+var (
+	_ QueryExecutor = (*RecordingExecutor)(nil)
+	_ QueryExecutor = (*ReplayExecutor)(nil)
+)
+
+// FakeHandler implements a query for FakeExecutor. It is created by the
+// generated HandleXxx functions. The handlers of paginated queries return all
+// the rows sorted by the keyset columns, EvalPage reads its page out of them.
+type FakeHandler struct {
+	Name  string
+	Apply func(q Query) error
+}
+
+// FakeExecutor runs queries with Go handlers instead of a database. Handlers
+// usually keep their state in the in-memory tables returned by TableOf.
+// Queries without a handler fail the test. It is safe for concurrent use.
+type FakeExecutor struct {
+	t interface {
+		Helper()
+		Errorf(format string, args ...any)
+	}
+	mu       sync.Mutex
+	handlers map[string]func(Query) error
+	tables   map[reflect.Type]fakeTable
+}
+
+func NewFakeExecutor(t interface {
+	Helper()
+	Errorf(format string, args ...any)
+}, handlers ...FakeHandler) *FakeExecutor {
+	f := &FakeExecutor{
+		t:        t,
+		handlers: map[string]func(Query) error{},
+		tables:   map[reflect.Type]fakeTable{},
+	}
+	f.Handle(handlers...)
+	return f
+}
+
+// Handle registers handlers, replacing the ones registered for the same
+// queries
+func (f *FakeExecutor) Handle(handlers ...FakeHandler) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, h := range handlers {
+		f.handlers[h.Name] = h.Apply
+	}
+}
+
+func (f *FakeExecutor) Execute(ctx context.Context, q Query) error {
+	f.t.Helper()
+	f.mu.Lock()
+	apply, ok := f.handlers[q.QueryName()]
+	f.mu.Unlock()
+
+	if !ok {
+		f.t.Errorf("no fake handler for query %s: SQL=%q", q.QueryName(), q.SQL())
+		return fmt.Errorf("no fake handler for query %s", q.QueryName())
+	}
+	return apply(q)
+}
+
+func (f *FakeExecutor) WithTx(ctx context.Context, fn func(QueryExecutor) error) error {
+	return f.WithTxOptions(ctx, TxOptions{}, fn)
+}
+
+// WithTxOptions snapshots the tables and runs fn against the fake executor
+// itself. The tables are restored when fn fails, nested calls behave like
+// savepoints.
+func (f *FakeExecutor) WithTxOptions(ctx context.Context, opts TxOptions, fn func(QueryExecutor) error) error {
+	restore := f.snapshot()
+	if err := fn(f); err != nil {
+		restore()
+		return err
+	}
+	return nil
+}
+
+func (f *FakeExecutor) snapshot() func() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	restores := make(map[reflect.Type]func(), len(f.tables))
+	for typ, table := range f.tables {
+		restores[typ] = table.snapshot()
+	}
+	return func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for typ, table := range f.tables {
+			if restore, ok := restores[typ]; ok {
+				restore()
+			} else {
+				table.truncate()
+			}
+		}
+	}
+}
+
+type fakeTable interface {
+	snapshot() (restore func())
+	truncate()
+}
+
+// FakeTable is an in-memory table of a FakeExecutor holding rows of T, usually
+// a generated model
+type FakeTable[T any] struct {
+	mu     sync.Mutex
+	rows   []T
+	nextID int64
+}
+
+// TableOf returns the table of f holding rows of T, creating it if needed
+func TableOf[T any](f *FakeExecutor) *FakeTable[T] {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	typ := reflect.TypeFor[T]()
+	if table, ok := f.tables[typ]; ok {
+		return table.(*FakeTable[T])
+	}
+	table := &FakeTable[T]{}
+	f.tables[typ] = table
+	return table
+}
+
+// NextID returns the next value of a sequence starting at 1, for handlers of
+// queries inserting rows with serial keys
+func (t *FakeTable[T]) NextID() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.nextID++
+	return t.nextID
+}
+
+func (t *FakeTable[T]) Insert(rows ...T) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rows = append(t.rows, rows...)
+}
+
+// Rows returns a copy of all rows in insertion order
+func (t *FakeTable[T]) Rows() []T {
+	return t.Filter(func(T) bool { return true })
+}
+
+// Find returns the first row accepted by match
+func (t *FakeTable[T]) Find(match func(T) bool) (T, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, row := range t.rows {
+		if match(row) {
+			return row, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// Filter returns the rows accepted by match
+func (t *FakeTable[T]) Filter(match func(T) bool) []T {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var rows []T
+	for _, row := range t.rows {
+		if match(row) {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// Update calls update on the rows accepted by match and returns their number
+func (t *FakeTable[T]) Update(match func(T) bool, update func(*T)) int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var n int64
+	for i := range t.rows {
+		if match(t.rows[i]) {
+			update(&t.rows[i])
+			n++
+		}
+	}
+	return n
+}
+
+// Delete removes the rows accepted by match and returns their number
+func (t *FakeTable[T]) Delete(match func(T) bool) int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	kept := t.rows[:0]
+	for _, row := range t.rows {
+		if !match(row) {
+			kept = append(kept, row)
+		}
+	}
+	n := int64(len(t.rows) - len(kept))
+	clear(t.rows[len(kept):])
+	t.rows = kept
+	return n
+}
+
+func (t *FakeTable[T]) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.rows)
+}
+
+func (t *FakeTable[T]) snapshot() func() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rows, nextID := append([]T(nil), t.rows...), t.nextID
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.rows, t.nextID = rows, nextID
+	}
+}
+
+func (t *FakeTable[T]) truncate() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rows, t.nextID = nil, 0
+}
+
+var _ QueryExecutor = (*FakeExecutor)(nil)
+
+type Step struct {
+	SQL string
+	// Args are compared with the query arguments using reflect.DeepEqual,
+	// except for ArgMatcher values, which match the argument themselves.
+	Args []any
+	// Apply is called with nil for the transaction steps created by
+	// ExpectBegin, ExpectCommit and ExpectRollback.
+	Apply func(Query) error
+
+	tx        string
+	times     int
+	hasTimes  bool
+	anyTimes  bool
+	unordered bool
+	group     []Step
+}
+
+// ExpectBegin expects WithTx to start a transaction. Once a stub has
+// transaction steps, every WithTx call must match ExpectBegin followed by
+// ExpectCommit, or ExpectRollback when its callback fails.
+func ExpectBegin() Step {
+	return Step{tx: "begin"}
+}
+
+// ExpectCommit expects WithTx to commit after its callback succeeded
+func ExpectCommit() Step {
+	return Step{tx: "commit"}
+}
+
+// ExpectRollback expects WithTx to roll back after its callback failed
+func ExpectRollback() Step {
+	return Step{tx: "rollback"}
+}
+
+// WillReturnError makes the step fail with err
+func (s Step) WillReturnError(err error) Step {
+	s.Apply = func(Query) error { return err }
+	return s
+}
+
+// ArgMatcher matches a single query argument of a Step
+type ArgMatcher interface {
+	Match(arg any) bool
+}
+
+type argMatcher struct {
+	desc  string
+	match func(any) bool
+}
+
+func (m argMatcher) Match(arg any) bool { return m.match(arg) }
+func (m argMatcher) String() string     { return m.desc }
+
+// Any matches every argument
+func Any() ArgMatcher {
+	return argMatcher{desc: "Any()", match: func(any) bool { return true }}
+}
+
+// Eq matches arguments deeply equal to want
+func Eq(want any) ArgMatcher {
+	return argMatcher{
+		desc:  fmt.Sprintf("Eq(%v)", want),
+		match: func(arg any) bool { return reflect.DeepEqual(arg, want) },
+	}
+}
+
+// ArgThat matches arguments for which fn returns true
+func ArgThat(fn func(arg any) bool) ArgMatcher {
+	return argMatcher{desc: "ArgThat(...)", match: fn}
+}
+
+// WithinDuration matches time arguments no further than d from want. It
+// accepts time.Time, *time.Time and driver.Valuer implementations producing a
+// time.Time, such as nullable time types.
+func WithinDuration(want time.Time, d time.Duration) ArgMatcher {
+	return argMatcher{
+		desc: fmt.Sprintf("WithinDuration(%v, %v)", want, d),
+		match: func(arg any) bool {
+			if v, ok := arg.(driver.Valuer); ok {
+				value, err := v.Value()
+				if err != nil {
+					return false
+				}
+				arg = value
+			}
+			var got time.Time
+			switch v := arg.(type) {
+			case time.Time:
+				got = v
+			case *time.Time:
+				if v == nil {
+					return false
+				}
+				got = *v
+			default:
+				return false
+			}
+			diff := got.Sub(want)
+			return diff >= -d && diff <= d
+		},
+	}
+}
+
+func argsMatch(want, got []any) bool {
+	if len(want) != len(got) {
+		return false
+	}
+	for i := range want {
+		if m, ok := want[i].(ArgMatcher); ok {
+			if !m.Match(got[i]) {
+				return false
+			}
+		} else if !reflect.DeepEqual(want[i], got[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Times expects the step to run exactly n times instead of once. Times(0)
+// expects the step to never run, negative values fail the test.
+func (s Step) Times(n int) Step {
+	s.times = n
+	s.hasTimes = true
+	s.anyTimes = false
+	return s
+}
+
+// AnyTimes expects the step to run any number of times, including never
+func (s Step) AnyTimes() Step {
+	s.anyTimes = true
+	return s
+}
+
+// InAnyOrder groups steps that may run in any order among themselves, e.g.
+// queries issued by concurrent goroutines. The group keeps its position in the
+// sequence of steps.
+func InAnyOrder(steps ...Step) Step {
+	return Step{unordered: true, group: steps}
+}
+
+type expectation struct {
+	step  Step
+	calls int
+}
+
+func (e *expectation) min() int {
+	switch {
+	case e.step.anyTimes:
+		return 0
+	case e.step.hasTimes:
+		return max(e.step.times, 0)
+	default:
+		return 1
+	}
+}
+
+func (e *expectation) exhausted() bool {
+	return !e.step.anyTimes && e.calls >= e.min()
+}
+
+func (e *expectation) matches(q Query) bool {
+	return e.step.tx == "" && e.step.SQL == q.SQL() && argsMatch(e.step.Args, queryArgs(q))
+}
+
+// queryArgs returns the arguments steps are matched against. Batch and
+// copyfrom queries have no Args and are matched against the slice passed to
+// Eval instead.
+func queryArgs(q Query) []any {
+	if q, ok := q.(interface{ stubArgs() []any }); ok {
+		return q.stubArgs()
+	}
+	return q.Args()
+}
+
+func expectationsOf(step Step) []*expectation {
+	if !step.unordered {
+		return []*expectation{{step: step}}
+	}
+	var out []*expectation
+	for _, s := range step.group {
+		out = append(out, expectationsOf(s)...)
+	}
+	return out
+}
+
+// stubGroup holds the expectations of one position in the sequence: a single
+// step in strict order, or all steps of an InAnyOrder group.
+type stubGroup []*expectation
+
+func (g stubGroup) satisfied() bool {
+	for _, e := range g {
+		if e.calls < e.min() {
+			return false
+		}
+	}
+	return true
+}
+
+func (g stubGroup) exhausted() bool {
+	for _, e := range g {
+		if !e.exhausted() {
+			return false
+		}
+	}
+	return true
+}
+
+// StubExecutor verifies queries are executed in expected order with expected arguments.
+// It is safe for concurrent use.
+type StubExecutor struct {
+	t interface {
+		Helper()
+		Errorf(format string, args ...any)
+	}
+	mu        sync.Mutex
+	groups    []stubGroup
+	i         int
+	txSteps   bool
+	txOptions []TxOptions
+}
+
+func NewStubExecutor(t interface {
+	Helper()
+	Errorf(format string, args ...any)
+}, steps ...Step) *StubExecutor {
+	s := &StubExecutor{t: t}
+	for _, step := range steps {
+		group := expectationsOf(step)
+		for _, e := range group {
+			s.txSteps = s.txSteps || e.step.tx != ""
+			if e.step.times < 0 {
+				t.Errorf("invalid step: Times(%d) for SQL=%q, use Times(0) for a step that must not run", e.step.times, e.step.SQL)
+			}
+		}
+		s.groups = append(s.groups, group)
+	}
+	return s
+}
+
+func (s *StubExecutor) Execute(ctx context.Context, q Query) error {
+	s.t.Helper()
+
+	step, err := s.next(q)
+	if err != nil {
+		return err
+	}
+	if step.Apply != nil {
+		return step.Apply(q)
+	}
+	return nil
+}
+
+// consume marks the first pending step accepted by match as executed. Groups
+// whose steps already ran often enough are skipped when match accepts none of
+// their steps. The caller must hold s.mu.
+func (s *StubExecutor) consume(match func(*expectation) bool) (Step, bool) {
+	for ; s.i < len(s.groups); s.i++ {
+		group := s.groups[s.i]
+		for _, e := range group {
+			if !e.exhausted() && match(e) {
+				e.calls++
+				if group.exhausted() {
+					s.i++
+				}
+				return e.step, true
+			}
+		}
+		if !group.satisfied() {
+			break
+		}
+	}
+	return Step{}, false
+}
+
+func (s *StubExecutor) next(q Query) (Step, error) {
+	s.t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if step, ok := s.consume(func(e *expectation) bool { return e.matches(q) }); ok {
+		return step, nil
+	}
+
+	if s.i >= len(s.groups) {
+		s.t.Errorf("unexpected query: SQL=%q Args=%v", q.SQL(), queryArgs(q))
+		return Step{}, fmt.Errorf("unexpected query")
+	}
+
+	group := s.groups[s.i]
+	if len(group) > 1 {
+		s.t.Errorf("no step of the unordered group at step %d matches query: SQL=%q Args=%v", s.i+1, q.SQL(), queryArgs(q))
+		return Step{}, fmt.Errorf("unexpected query")
+	}
+	step := group[0].step
+	if step.tx != "" {
+		s.t.Errorf("expected %s at step %d, got query: SQL=%q Args=%v", step.tx, s.i+1, q.SQL(), queryArgs(q))
+		return Step{}, fmt.Errorf("unexpected query")
+	}
+	if q.SQL() != step.SQL {
+		s.t.Errorf("SQL mismatch at step %d:\nwant: %q\ngot:  %q", s.i+1, step.SQL, q.SQL())
+		return Step{}, fmt.Errorf("SQL mismatch")
+	}
+	s.t.Errorf("Args mismatch at step %d:\nwant: %v\ngot:  %v", s.i+1, step.Args, queryArgs(q))
+	return Step{}, fmt.Errorf("args mismatch")
+}
+
+// expectTx consumes the transaction step for event, unless the stub has no
+// transaction steps at all
+func (s *StubExecutor) expectTx(event string) error {
+	s.t.Helper()
+	s.mu.Lock()
+	if !s.txSteps {
+		s.mu.Unlock()
+		return nil
+	}
+	step, ok := s.consume(func(e *expectation) bool { return e.step.tx == event })
+	i := s.i
+	s.mu.Unlock()
+
+	if !ok {
+		s.t.Errorf("unexpected %s at step %d", event, i+1)
+		return fmt.Errorf("unexpected %s", event)
+	}
+	if step.Apply != nil {
+		return step.Apply(nil)
+	}
+	return nil
+}
+
+func (s *StubExecutor) AssertDone() {
+	s.t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	done := 0
+	for _, group := range s.groups {
+		if group.satisfied() {
+			done++
+		}
+	}
+	if done != len(s.groups) {
+		s.t.Errorf("not all steps executed: %d/%d", done, len(s.groups))
+	}
+}
+
+func (s *StubExecutor) WithTx(ctx context.Context, fn func(QueryExecutor) error) error {
+	return s.WithTxOptions(ctx, TxOptions{}, fn)
+}
+
+// WithTxOptions records opts and runs fn against the stub itself. When the
+// stub has transaction steps, it also checks that the transaction begins and
+// then commits, or rolls back when fn fails.
+func (s *StubExecutor) WithTxOptions(ctx context.Context, opts TxOptions, fn func(QueryExecutor) error) error {
+	s.t.Helper()
+	s.mu.Lock()
+	s.txOptions = append(s.txOptions, opts)
+	s.mu.Unlock()
+
+	if err := s.expectTx("begin"); err != nil {
+		return err
+	}
+	if err := fn(s); err != nil {
+		if rbErr := s.expectTx("rollback"); rbErr != nil {
+			return fmt.Errorf("tx failed: %w, rollback failed: %v", err, rbErr)
+		}
+		return err
+	}
+	return s.expectTx("commit")
+}
+
+// TxOptions returns the options of every transaction started on the stub, in order
+func (s *StubExecutor) TxOptions() []TxOptions {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]TxOptions(nil), s.txOptions...)
 }
 
 var _ QueryExecutor = (*StubExecutor)(nil)
@@ -5,6 +5,8 @@
 package db
 
 import (
+	"fmt"
+	"log/slog"
 	"time"
 )
 
@@ -22,3 +24,23 @@ type User struct {
 	Email     string    `json:"email"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+// String implements fmt.Stringer and masks sensitive fields.
+func (v User) String() string {
+	return fmt.Sprintf("{ID:%v Name:%v Email:[REDACTED] CreatedAt:%v}", v.ID, v.Name, v.CreatedAt)
+}
+
+// GoString implements fmt.GoStringer and masks sensitive fields.
+func (v User) GoString() string {
+	return fmt.Sprintf("User{ID:%#v, Name:%#v, Email:\"[REDACTED]\", CreatedAt:%#v}", v.ID, v.Name, v.CreatedAt)
+}
+
+// LogValue implements slog.LogValuer and masks sensitive fields.
+func (v User) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Any("ID", v.ID),
+		slog.Any("Name", v.Name),
+		slog.String("Email", "[REDACTED]"),
+		slog.Any("CreatedAt", v.CreatedAt),
+	)
+}
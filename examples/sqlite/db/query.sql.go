@@ -8,6 +8,13 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"log/slog"
+	"strings"
+	"time"
 )
 
 const countUsers = `-- name: CountUsers :one
@@ -23,6 +30,23 @@ func (q *CountUsersQuery) SQL() string {
 	return countUsers
 }
 
+func (q *CountUsersQuery) QueryName() string {
+	return "CountUsers"
+}
+
+func (q *CountUsersQuery) QueryCmd() string {
+	return ":one"
+}
+
+func (q *CountUsersQuery) SourceFile() string {
+	return "query.sql"
+}
+
+// UsesPrimary makes RoutingExecutor run the query on the primary
+func (q *CountUsersQuery) UsesPrimary() bool {
+	return true
+}
+
 func (q *CountUsersQuery) Args() []any {
 	return nil
 }
@@ -49,6 +73,21 @@ func (q *CountUsersQuery) Eval(ctx context.Context) (int64, error) {
 func NewCountUsersQuery(ex QueryExecutor) *CountUsersQuery {
 	return &CountUsersQuery{ex: ex}
 }
+
+func HandleCountUsers(fn func() (int64, error)) FakeHandler {
+	return FakeHandler{
+		Name: "CountUsers",
+		Apply: func(q Query) error {
+			query := q.(*CountUsersQuery)
+			result, err := fn()
+			if err != nil {
+				return err
+			}
+			query.SetResult(result)
+			return nil
+		},
+	}
+}
 func ExpectCountUsers(result int64, err error) Step {
 	return Step{
 		SQL:  countUsers,
@@ -82,6 +121,23 @@ func (q *CreatePostQuery) SQL() string {
 	return createPost
 }
 
+func (q *CreatePostQuery) QueryName() string {
+	return "CreatePost"
+}
+
+func (q *CreatePostQuery) QueryCmd() string {
+	return ":one"
+}
+
+func (q *CreatePostQuery) SourceFile() string {
+	return "query.sql"
+}
+
+// UsesPrimary makes RoutingExecutor run the query on the primary
+func (q *CreatePostQuery) UsesPrimary() bool {
+	return true
+}
+
 func (q *CreatePostQuery) Args() []any {
 	return []any{q.arg.AuthorID, q.arg.Title, q.arg.Body}
 }
@@ -115,6 +171,21 @@ func (q *CreatePostQuery) Eval(ctx context.Context, arg CreatePostParams) (Post,
 func NewCreatePostQuery(ex QueryExecutor) *CreatePostQuery {
 	return &CreatePostQuery{ex: ex}
 }
+
+func HandleCreatePost(fn func(arg CreatePostParams) (Post, error)) FakeHandler {
+	return FakeHandler{
+		Name: "CreatePost",
+		Apply: func(q Query) error {
+			query := q.(*CreatePostQuery)
+			result, err := fn(query.arg)
+			if err != nil {
+				return err
+			}
+			query.SetResult(result)
+			return nil
+		},
+	}
+}
 func ExpectCreatePost(arg CreatePostParams, result Post, err error) Step {
 	return Step{
 		SQL:  createPost,
@@ -126,6 +197,17 @@ func ExpectCreatePost(arg CreatePostParams, result Post, err error) Step {
 	}
 }
 
+func ExpectCreatePostMatching(authorID, title, body ArgMatcher, result Post, err error) Step {
+	return Step{
+		SQL:  createPost,
+		Args: []any{authorID, title, body},
+		Apply: func(q Query) error {
+			q.(*CreatePostQuery).SetResult(result)
+			return err
+		},
+	}
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (name, email)
 VALUES (?, ?)
@@ -143,10 +225,32 @@ func (q *CreateUserQuery) SQL() string {
 	return createUser
 }
 
+func (q *CreateUserQuery) QueryName() string {
+	return "CreateUser"
+}
+
+func (q *CreateUserQuery) QueryCmd() string {
+	return ":one"
+}
+
+func (q *CreateUserQuery) SourceFile() string {
+	return "query.sql"
+}
+
+// UsesPrimary makes RoutingExecutor run the query on the primary
+func (q *CreateUserQuery) UsesPrimary() bool {
+	return true
+}
+
 func (q *CreateUserQuery) Args() []any {
 	return []any{q.name, q.email}
 }
 
+// LogArgs returns Args with the values of columns missing from log_args_allowlist redacted
+func (q *CreateUserQuery) LogArgs() []any {
+	return []any{q.name, RedactedArg}
+}
+
 func (q *CreateUserQuery) Scan(row *sql.Row) error {
 	return row.Scan(
 		&q.result.ID,
@@ -176,6 +280,21 @@ func (q *CreateUserQuery) Eval(ctx context.Context, name string, email string) (
 func NewCreateUserQuery(ex QueryExecutor) *CreateUserQuery {
 	return &CreateUserQuery{ex: ex}
 }
+
+func HandleCreateUser(fn func(name string, email string) (User, error)) FakeHandler {
+	return FakeHandler{
+		Name: "CreateUser",
+		Apply: func(q Query) error {
+			query := q.(*CreateUserQuery)
+			result, err := fn(query.name, query.email)
+			if err != nil {
+				return err
+			}
+			query.SetResult(result)
+			return nil
+		},
+	}
+}
 func ExpectCreateUser(name string, email string, result User, err error) Step {
 	return Step{
 		SQL:  createUser,
@@ -187,6 +306,17 @@ func ExpectCreateUser(name string, email string, result User, err error) Step {
 	}
 }
 
+func ExpectCreateUserMatching(name, email ArgMatcher, result User, err error) Step {
+	return Step{
+		SQL:  createUser,
+		Args: []any{name, email},
+		Apply: func(q Query) error {
+			q.(*CreateUserQuery).SetResult(result)
+			return err
+		},
+	}
+}
+
 const createUserGetID = `-- name: CreateUserGetID :execlastid
 INSERT INTO users (name, email)
 VALUES (?, ?)
@@ -204,10 +334,27 @@ func (q *CreateUserGetIDQuery) SQL() string {
 	return createUserGetID
 }
 
+func (q *CreateUserGetIDQuery) QueryName() string {
+	return "CreateUserGetID"
+}
+
+func (q *CreateUserGetIDQuery) QueryCmd() string {
+	return ":execlastid"
+}
+
+func (q *CreateUserGetIDQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *CreateUserGetIDQuery) Args() []any {
 	return []any{q.name, q.email}
 }
 
+// LogArgs returns Args with the values of columns missing from log_args_allowlist redacted
+func (q *CreateUserGetIDQuery) LogArgs() []any {
+	return []any{q.name, RedactedArg}
+}
+
 func (q *CreateUserGetIDQuery) SetLastInsertID(n int64) {
 	q.lastID = n
 }
@@ -227,6 +374,21 @@ func (q *CreateUserGetIDQuery) Eval(ctx context.Context, name string, email stri
 func NewCreateUserGetIDQuery(ex QueryExecutor) *CreateUserGetIDQuery {
 	return &CreateUserGetIDQuery{ex: ex}
 }
+
+func HandleCreateUserGetID(fn func(name string, email string) (int64, error)) FakeHandler {
+	return FakeHandler{
+		Name: "CreateUserGetID",
+		Apply: func(q Query) error {
+			query := q.(*CreateUserGetIDQuery)
+			lastID, err := fn(query.name, query.email)
+			if err != nil {
+				return err
+			}
+			query.SetLastInsertID(lastID)
+			return nil
+		},
+	}
+}
 func ExpectCreateUserGetID(name string, email string, lastID int64, err error) Step {
 	return Step{
 		SQL:  createUserGetID,
@@ -238,6 +400,108 @@ func ExpectCreateUserGetID(name string, email string, lastID int64, err error) S
 	}
 }
 
+func ExpectCreateUserGetIDMatching(name, email ArgMatcher, lastID int64, err error) Step {
+	return Step{
+		SQL:  createUserGetID,
+		Args: []any{name, email},
+		Apply: func(q Query) error {
+			q.(*CreateUserGetIDQuery).SetLastInsertID(lastID)
+			return err
+		},
+	}
+}
+
+const deleteAuthorPosts = `-- name: DeleteAuthorPosts :execrows
+DELETE FROM posts
+WHERE author_id = ? AND id IN (/*SLICE:ids*/?)
+`
+
+type DeleteAuthorPostsQuery struct {
+	ex           QueryExecutor
+	authorID     int64
+	ids          []int64
+	rowsAffected int64
+}
+
+func (q *DeleteAuthorPostsQuery) SQL() string {
+	query := deleteAuthorPosts
+	if len(q.ids) > 0 {
+		query = strings.Replace(query, "/*SLICE:ids*/?", strings.Repeat(",?", len(q.ids))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:ids*/?", "NULL", 1)
+	}
+	return query
+}
+
+func (q *DeleteAuthorPostsQuery) QueryName() string {
+	return "DeleteAuthorPosts"
+}
+
+func (q *DeleteAuthorPostsQuery) QueryCmd() string {
+	return ":execrows"
+}
+
+func (q *DeleteAuthorPostsQuery) SourceFile() string {
+	return "query.sql"
+}
+
+// HasSqlcSlices keeps the query out of the prepared statement cache, its SQL
+// depends on the length of its sqlc.slice arguments
+func (q *DeleteAuthorPostsQuery) HasSqlcSlices() bool {
+	return true
+}
+
+func (q *DeleteAuthorPostsQuery) Args() []any {
+	var args []any
+	args = append(args, q.authorID)
+	for _, v := range q.ids {
+		args = append(args, v)
+	}
+	return args
+}
+
+func (q *DeleteAuthorPostsQuery) SetRowsAffected(n int64) {
+	q.rowsAffected = n
+}
+func (q *DeleteAuthorPostsQuery) Eval(ctx context.Context, authorID int64, ids []int64) (int64, error) {
+	q.authorID = authorID
+	q.ids = ids
+	if err := q.ex.Execute(ctx, q); err != nil {
+		return 0, err
+	}
+	return q.rowsAffected, nil
+}
+
+func NewDeleteAuthorPostsQuery(ex QueryExecutor) *DeleteAuthorPostsQuery {
+	return &DeleteAuthorPostsQuery{ex: ex}
+}
+
+func HandleDeleteAuthorPosts(fn func(authorID int64, ids []int64) (int64, error)) FakeHandler {
+	return FakeHandler{
+		Name: "DeleteAuthorPosts",
+		Apply: func(q Query) error {
+			query := q.(*DeleteAuthorPostsQuery)
+			rowsAffected, err := fn(query.authorID, query.ids)
+			if err != nil {
+				return err
+			}
+			query.SetRowsAffected(rowsAffected)
+			return nil
+		},
+	}
+}
+func ExpectDeleteAuthorPosts(authorID int64, ids []int64, rowsAffected int64, err error) Step {
+	expanded := &DeleteAuthorPostsQuery{authorID: authorID, ids: ids}
+	return Step{
+		SQL:  expanded.SQL(),
+		Args: expanded.Args(),
+		Apply: func(q Query) error {
+			q.(*DeleteAuthorPostsQuery).SetRowsAffected(rowsAffected)
+			return err
+		},
+	}
+}
+
 const deleteUser = `-- name: DeleteUser :exec
 DELETE FROM users
 WHERE id = ?
@@ -253,10 +517,27 @@ func (q *DeleteUserQuery) SQL() string {
 	return deleteUser
 }
 
+func (q *DeleteUserQuery) QueryName() string {
+	return "DeleteUser"
+}
+
+func (q *DeleteUserQuery) QueryCmd() string {
+	return ":exec"
+}
+
+func (q *DeleteUserQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *DeleteUserQuery) Args() []any {
 	return []any{q.id}
 }
 
+// LogArgs returns Args with the values of columns missing from log_args_allowlist redacted
+func (q *DeleteUserQuery) LogArgs() []any {
+	return []any{q.id}
+}
+
 func (q *DeleteUserQuery) SetRowsAffected(n int64) {
 	q.rowsAffected = n
 }
@@ -268,6 +549,16 @@ func (q *DeleteUserQuery) Eval(ctx context.Context, id int64) error {
 func NewDeleteUserQuery(ex QueryExecutor) *DeleteUserQuery {
 	return &DeleteUserQuery{ex: ex}
 }
+
+func HandleDeleteUser(fn func(id int64) error) FakeHandler {
+	return FakeHandler{
+		Name: "DeleteUser",
+		Apply: func(q Query) error {
+			query := q.(*DeleteUserQuery)
+			return fn(query.id)
+		},
+	}
+}
 func ExpectDeleteUser(id int64, err error) Step {
 	return Step{
 		SQL:  deleteUser,
@@ -278,6 +569,16 @@ func ExpectDeleteUser(id int64, err error) Step {
 	}
 }
 
+func ExpectDeleteUserMatching(id ArgMatcher, err error) Step {
+	return Step{
+		SQL:  deleteUser,
+		Args: []any{id},
+		Apply: func(q Query) error {
+			return err
+		},
+	}
+}
+
 const getPostWithAuthor = `-- name: GetPostWithAuthor :one
 SELECT posts.id, posts.author_id, posts.title, posts.body, posts.created_at, users.id, users.name, users.email, users.created_at
 FROM posts
@@ -290,6 +591,24 @@ type GetPostWithAuthorRow struct {
 	User User `json:"user"`
 }
 
+// String implements fmt.Stringer and masks sensitive fields.
+func (v GetPostWithAuthorRow) String() string {
+	return fmt.Sprintf("{Post:%v User:%v}", v.Post, v.User)
+}
+
+// GoString implements fmt.GoStringer and masks sensitive fields.
+func (v GetPostWithAuthorRow) GoString() string {
+	return fmt.Sprintf("GetPostWithAuthorRow{Post:%#v, User:%#v}", v.Post, v.User)
+}
+
+// LogValue implements slog.LogValuer and masks sensitive fields.
+func (v GetPostWithAuthorRow) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Any("Post", v.Post),
+		slog.Any("User", v.User),
+	)
+}
+
 type GetPostWithAuthorQuery struct {
 	ex     QueryExecutor
 	id     int64
@@ -300,6 +619,18 @@ func (q *GetPostWithAuthorQuery) SQL() string {
 	return getPostWithAuthor
 }
 
+func (q *GetPostWithAuthorQuery) QueryName() string {
+	return "GetPostWithAuthor"
+}
+
+func (q *GetPostWithAuthorQuery) QueryCmd() string {
+	return ":one"
+}
+
+func (q *GetPostWithAuthorQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *GetPostWithAuthorQuery) Args() []any {
 	return []any{q.id}
 }
@@ -337,6 +668,21 @@ func (q *GetPostWithAuthorQuery) Eval(ctx context.Context, id int64) (GetPostWit
 func NewGetPostWithAuthorQuery(ex QueryExecutor) *GetPostWithAuthorQuery {
 	return &GetPostWithAuthorQuery{ex: ex}
 }
+
+func HandleGetPostWithAuthor(fn func(id int64) (GetPostWithAuthorRow, error)) FakeHandler {
+	return FakeHandler{
+		Name: "GetPostWithAuthor",
+		Apply: func(q Query) error {
+			query := q.(*GetPostWithAuthorQuery)
+			result, err := fn(query.id)
+			if err != nil {
+				return err
+			}
+			query.SetResult(result)
+			return nil
+		},
+	}
+}
 func ExpectGetPostWithAuthor(id int64, result GetPostWithAuthorRow, err error) Step {
 	return Step{
 		SQL:  getPostWithAuthor,
@@ -348,6 +694,17 @@ func ExpectGetPostWithAuthor(id int64, result GetPostWithAuthorRow, err error) S
 	}
 }
 
+func ExpectGetPostWithAuthorMatching(id ArgMatcher, result GetPostWithAuthorRow, err error) Step {
+	return Step{
+		SQL:  getPostWithAuthor,
+		Args: []any{id},
+		Apply: func(q Query) error {
+			q.(*GetPostWithAuthorQuery).SetResult(result)
+			return err
+		},
+	}
+}
+
 const getUser = `-- name: GetUser :one
 SELECT id, name, email, created_at FROM users
 WHERE id = ?
@@ -363,10 +720,27 @@ func (q *GetUserQuery) SQL() string {
 	return getUser
 }
 
+func (q *GetUserQuery) QueryName() string {
+	return "GetUser"
+}
+
+func (q *GetUserQuery) QueryCmd() string {
+	return ":one"
+}
+
+func (q *GetUserQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *GetUserQuery) Args() []any {
 	return []any{q.id}
 }
 
+// LogArgs returns Args with the values of columns missing from log_args_allowlist redacted
+func (q *GetUserQuery) LogArgs() []any {
+	return []any{q.id}
+}
+
 func (q *GetUserQuery) Scan(row *sql.Row) error {
 	return row.Scan(
 		&q.result.ID,
@@ -395,6 +769,21 @@ func (q *GetUserQuery) Eval(ctx context.Context, id int64) (User, error) {
 func NewGetUserQuery(ex QueryExecutor) *GetUserQuery {
 	return &GetUserQuery{ex: ex}
 }
+
+func HandleGetUser(fn func(id int64) (User, error)) FakeHandler {
+	return FakeHandler{
+		Name: "GetUser",
+		Apply: func(q Query) error {
+			query := q.(*GetUserQuery)
+			result, err := fn(query.id)
+			if err != nil {
+				return err
+			}
+			query.SetResult(result)
+			return nil
+		},
+	}
+}
 func ExpectGetUser(id int64, result User, err error) Step {
 	return Step{
 		SQL:  getUser,
@@ -406,6 +795,17 @@ func ExpectGetUser(id int64, result User, err error) Step {
 	}
 }
 
+func ExpectGetUserMatching(id ArgMatcher, result User, err error) Step {
+	return Step{
+		SQL:  getUser,
+		Args: []any{id},
+		Apply: func(q Query) error {
+			q.(*GetUserQuery).SetResult(result)
+			return err
+		},
+	}
+}
+
 const listPostsWithAuthor = `-- name: ListPostsWithAuthor :many
 SELECT posts.id, posts.author_id, posts.title, posts.body, posts.created_at, users.id, users.name, users.email, users.created_at
 FROM posts
@@ -418,6 +818,24 @@ type ListPostsWithAuthorRow struct {
 	User User `json:"user"`
 }
 
+// String implements fmt.Stringer and masks sensitive fields.
+func (v ListPostsWithAuthorRow) String() string {
+	return fmt.Sprintf("{Post:%v User:%v}", v.Post, v.User)
+}
+
+// GoString implements fmt.GoStringer and masks sensitive fields.
+func (v ListPostsWithAuthorRow) GoString() string {
+	return fmt.Sprintf("ListPostsWithAuthorRow{Post:%#v, User:%#v}", v.Post, v.User)
+}
+
+// LogValue implements slog.LogValuer and masks sensitive fields.
+func (v ListPostsWithAuthorRow) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Any("Post", v.Post),
+		slog.Any("User", v.User),
+	)
+}
+
 type ListPostsWithAuthorQuery struct {
 	ex      QueryExecutor
 	results []ListPostsWithAuthorRow
@@ -427,6 +845,18 @@ func (q *ListPostsWithAuthorQuery) SQL() string {
 	return listPostsWithAuthor
 }
 
+func (q *ListPostsWithAuthorQuery) QueryName() string {
+	return "ListPostsWithAuthor"
+}
+
+func (q *ListPostsWithAuthorQuery) QueryCmd() string {
+	return ":many"
+}
+
+func (q *ListPostsWithAuthorQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *ListPostsWithAuthorQuery) Args() []any {
 	return nil
 }
@@ -465,15 +895,80 @@ func (q *ListPostsWithAuthorQuery) Eval(ctx context.Context) ([]ListPostsWithAut
 	return q.Results(), nil
 }
 
+// listPostsWithAuthorStream hands ListPostsWithAuthor rows to an Iter loop one at a time.
+type listPostsWithAuthorStream struct {
+	*ListPostsWithAuthorQuery
+	yield   func(ListPostsWithAuthorRow, error) bool
+	stopped bool
+}
+
+func (s *listPostsWithAuthorStream) ScanNext(row *sql.Rows) (bool, error) {
+	var i ListPostsWithAuthorRow
+	if err := row.Scan(
+		&i.Post.ID,
+		&i.Post.AuthorID,
+		&i.Post.Title,
+		&i.Post.Body,
+		&i.Post.CreatedAt,
+		&i.User.ID,
+		&i.User.Name,
+		&i.User.Email,
+		&i.User.CreatedAt,
+	); err != nil {
+		return false, err
+	}
+	s.stopped = !s.yield(i, nil)
+	return !s.stopped, nil
+}
+
+// SetResults yields results provided by a StubExecutor step.
+func (s *listPostsWithAuthorStream) SetResults(results []ListPostsWithAuthorRow) {
+	for _, r := range results {
+		if s.stopped = !s.yield(r, nil); s.stopped {
+			return
+		}
+	}
+}
+
+// Iter executes the query and yields rows as they are scanned. The rows are
+// closed when the loop finishes or breaks early.
+func (q *ListPostsWithAuthorQuery) Iter(ctx context.Context) iter.Seq2[ListPostsWithAuthorRow, error] {
+	return func(yield func(ListPostsWithAuthorRow, error) bool) {
+		s := &listPostsWithAuthorStream{ListPostsWithAuthorQuery: q, yield: yield}
+		if err := q.ex.Execute(ctx, s); err != nil && !s.stopped {
+			var zero ListPostsWithAuthorRow
+			yield(zero, err)
+		}
+	}
+}
+
 func NewListPostsWithAuthorQuery(ex QueryExecutor) *ListPostsWithAuthorQuery {
 	return &ListPostsWithAuthorQuery{ex: ex}
 }
+
+func HandleListPostsWithAuthor(fn func() ([]ListPostsWithAuthorRow, error)) FakeHandler {
+	return FakeHandler{
+		Name: "ListPostsWithAuthor",
+		Apply: func(q Query) error {
+			results, err := fn()
+			if err != nil {
+				return err
+			}
+			q.(interface {
+				SetResults([]ListPostsWithAuthorRow)
+			}).SetResults(results)
+			return nil
+		},
+	}
+}
 func ExpectListPostsWithAuthor(results []ListPostsWithAuthorRow, err error) Step {
 	return Step{
 		SQL:  listPostsWithAuthor,
 		Args: nil,
 		Apply: func(q Query) error {
-			q.(*ListPostsWithAuthorQuery).SetResults(results)
+			q.(interface {
+				SetResults([]ListPostsWithAuthorRow)
+			}).SetResults(results)
 			return err
 		},
 	}
@@ -493,6 +988,23 @@ func (q *ListUsersQuery) SQL() string {
 	return listUsers
 }
 
+func (q *ListUsersQuery) QueryName() string {
+	return "ListUsers"
+}
+
+func (q *ListUsersQuery) QueryCmd() string {
+	return ":many"
+}
+
+func (q *ListUsersQuery) SourceFile() string {
+	return "query.sql"
+}
+
+// Timeout bounds the execution of the query, see "-- @timeout"
+func (q *ListUsersQuery) Timeout() time.Duration {
+	return 5 * time.Second
+}
+
 func (q *ListUsersQuery) Args() []any {
 	return nil
 }
@@ -526,15 +1038,529 @@ func (q *ListUsersQuery) Eval(ctx context.Context) ([]User, error) {
 	return q.Results(), nil
 }
 
+// listUsersStream hands ListUsers rows to an Iter loop one at a time.
+type listUsersStream struct {
+	*ListUsersQuery
+	yield   func(User, error) bool
+	stopped bool
+}
+
+func (s *listUsersStream) ScanNext(row *sql.Rows) (bool, error) {
+	var i User
+	if err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.CreatedAt,
+	); err != nil {
+		return false, err
+	}
+	s.stopped = !s.yield(i, nil)
+	return !s.stopped, nil
+}
+
+// SetResults yields results provided by a StubExecutor step.
+func (s *listUsersStream) SetResults(results []User) {
+	for _, r := range results {
+		if s.stopped = !s.yield(r, nil); s.stopped {
+			return
+		}
+	}
+}
+
+// Iter executes the query and yields rows as they are scanned. The rows are
+// closed when the loop finishes or breaks early.
+func (q *ListUsersQuery) Iter(ctx context.Context) iter.Seq2[User, error] {
+	return func(yield func(User, error) bool) {
+		s := &listUsersStream{ListUsersQuery: q, yield: yield}
+		if err := q.ex.Execute(ctx, s); err != nil && !s.stopped {
+			var zero User
+			yield(zero, err)
+		}
+	}
+}
+
 func NewListUsersQuery(ex QueryExecutor) *ListUsersQuery {
 	return &ListUsersQuery{ex: ex}
 }
+
+func HandleListUsers(fn func() ([]User, error)) FakeHandler {
+	return FakeHandler{
+		Name: "ListUsers",
+		Apply: func(q Query) error {
+			results, err := fn()
+			if err != nil {
+				return err
+			}
+			q.(interface{ SetResults([]User) }).SetResults(results)
+			return nil
+		},
+	}
+}
 func ExpectListUsers(results []User, err error) Step {
 	return Step{
 		SQL:  listUsers,
 		Args: nil,
 		Apply: func(q Query) error {
-			q.(*ListUsersQuery).SetResults(results)
+			q.(interface{ SetResults([]User) }).SetResults(results)
+			return err
+		},
+	}
+}
+
+const listUsersByID = `-- name: ListUsersByID :many
+SELECT id, name, email, created_at FROM users
+ORDER BY id
+`
+
+type ListUsersByIDQuery struct {
+	ex      QueryExecutor
+	results []User
+}
+
+func (q *ListUsersByIDQuery) SQL() string {
+	return listUsersByID
+}
+
+func (q *ListUsersByIDQuery) QueryName() string {
+	return "ListUsersByID"
+}
+
+func (q *ListUsersByIDQuery) QueryCmd() string {
+	return ":many"
+}
+
+func (q *ListUsersByIDQuery) SourceFile() string {
+	return "query.sql"
+}
+
+func (q *ListUsersByIDQuery) Args() []any {
+	return nil
+}
+
+func (q *ListUsersByIDQuery) ScanRow(row *sql.Rows) error {
+	var i User
+	if err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.CreatedAt,
+	); err != nil {
+		return err
+	}
+	q.results = append(q.results, i)
+	return nil
+}
+
+func (q *ListUsersByIDQuery) Results() []User {
+	return q.results
+}
+
+func (q *ListUsersByIDQuery) SetResults(results []User) {
+	q.results = results
+}
+func (q *ListUsersByIDQuery) Eval(ctx context.Context) ([]User, error) {
+	q.results = nil
+	if err := q.ex.Execute(ctx, q); err != nil {
+		return nil, err
+	}
+	return q.Results(), nil
+}
+
+// listUsersByIDStream hands ListUsersByID rows to an Iter loop one at a time.
+type listUsersByIDStream struct {
+	*ListUsersByIDQuery
+	yield   func(User, error) bool
+	stopped bool
+}
+
+func (s *listUsersByIDStream) ScanNext(row *sql.Rows) (bool, error) {
+	var i User
+	if err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.CreatedAt,
+	); err != nil {
+		return false, err
+	}
+	s.stopped = !s.yield(i, nil)
+	return !s.stopped, nil
+}
+
+// SetResults yields results provided by a StubExecutor step.
+func (s *listUsersByIDStream) SetResults(results []User) {
+	for _, r := range results {
+		if s.stopped = !s.yield(r, nil); s.stopped {
+			return
+		}
+	}
+}
+
+// Iter executes the query and yields rows as they are scanned. The rows are
+// closed when the loop finishes or breaks early.
+func (q *ListUsersByIDQuery) Iter(ctx context.Context) iter.Seq2[User, error] {
+	return func(yield func(User, error) bool) {
+		s := &listUsersByIDStream{ListUsersByIDQuery: q, yield: yield}
+		if err := q.ex.Execute(ctx, s); err != nil && !s.stopped {
+			var zero User
+			yield(zero, err)
+		}
+	}
+}
+
+func NewListUsersByIDQuery(ex QueryExecutor) *ListUsersByIDQuery {
+	return &ListUsersByIDQuery{ex: ex}
+}
+
+const listUsersByIDFirstPage = `-- name: ListUsersByID :many
+SELECT * FROM (
+SELECT id, name, email, created_at FROM users
+ORDER BY id
+) AS page
+ORDER BY id ASC
+LIMIT ?
+`
+
+const listUsersByIDNextPage = `-- name: ListUsersByID :many
+SELECT * FROM (
+SELECT id, name, email, created_at FROM users
+ORDER BY id
+) AS page
+WHERE (id) > (?)
+ORDER BY id ASC
+LIMIT ?
+`
+
+// ListUsersByIDPage is a page of ListUsersByID rows returned by EvalPage
+type ListUsersByIDPage struct {
+	Items []User
+	// NextCursor reads the following page, it is empty on the last page
+	NextCursor string
+	HasMore    bool
+}
+
+// EncodeListUsersByIDCursor returns the opaque cursor of the page following
+// the row with the given keyset values
+func EncodeListUsersByIDCursor(id int64) (string, error) {
+	data, err := json.Marshal([]any{id})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeListUsersByIDCursor returns the keyset values of a cursor created by
+// EncodeListUsersByIDCursor
+func DecodeListUsersByIDCursor(cursor string) (id int64, err error) {
+	var values []json.RawMessage
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil {
+		err = json.Unmarshal(data, &values)
+	}
+	if err == nil && len(values) != 1 {
+		err = fmt.Errorf("got %d values, want 1", len(values))
+	}
+	if err == nil {
+		err = json.Unmarshal(values[0], &id)
+	}
+	if err != nil {
+		err = fmt.Errorf("invalid ListUsersByID cursor: %w", err)
+	}
+	return id, err
+}
+
+// listUsersByIDPageQuery reads a single page of ListUsersByID
+type listUsersByIDPageQuery struct {
+	*ListUsersByIDQuery
+	after  string
+	cursor []any
+	limit  int
+}
+
+// newListUsersByIDPageQuery returns the query reading the page of q
+// following cursor
+func newListUsersByIDPageQuery(q *ListUsersByIDQuery, cursor string, limit int) (*listUsersByIDPageQuery, error) {
+	page := &listUsersByIDPageQuery{ListUsersByIDQuery: q, after: cursor, limit: limit}
+	if cursor != "" {
+		id, err := DecodeListUsersByIDCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		page.cursor = []any{id}
+	}
+	return page, nil
+}
+
+func (q *listUsersByIDPageQuery) SQL() string {
+	if q.cursor != nil {
+		return listUsersByIDNextPage
+	}
+	return listUsersByIDFirstPage
+}
+
+func (q *listUsersByIDPageQuery) Args() []any {
+	args := append(q.ListUsersByIDQuery.Args(), q.cursor...)
+	return append(args, q.limit+1)
+}
+
+// EvalPage returns at most limit rows following cursor, sorted by the
+// keyset columns. An empty cursor returns the first page.
+func (q *ListUsersByIDQuery) EvalPage(ctx context.Context, cursor string, limit int) (ListUsersByIDPage, error) {
+	if limit <= 0 {
+		return ListUsersByIDPage{}, fmt.Errorf("ListUsersByID: limit must be positive, got %d", limit)
+	}
+	page, err := newListUsersByIDPageQuery(q, cursor, limit)
+	if err != nil {
+		return ListUsersByIDPage{}, err
+	}
+	q.results = nil
+	if err := q.ex.Execute(ctx, page); err != nil {
+		return ListUsersByIDPage{}, err
+	}
+	result := ListUsersByIDPage{Items: q.Results()}
+	if len(result.Items) > limit {
+		last := result.Items[limit-1]
+		result.Items, result.HasMore = result.Items[:limit], true
+		next, err := EncodeListUsersByIDCursor(last.ID)
+		if err != nil {
+			return ListUsersByIDPage{}, err
+		}
+		result.NextCursor = next
+	}
+	return result, nil
+}
+
+// slice returns the rows of the page out of all the rows of the query, like
+// the database does: at most limit+1 rows following the row the cursor was
+// created from. rows must be sorted by the keyset columns.
+func (q *listUsersByIDPageQuery) slice(rows []User) ([]User, error) {
+	if q.after != "" {
+		i := 0
+		for ; i < len(rows); i++ {
+			last := rows[i]
+			cursor, err := EncodeListUsersByIDCursor(last.ID)
+			if err != nil {
+				return nil, err
+			}
+			if cursor == q.after {
+				break
+			}
+		}
+		rows = rows[min(i+1, len(rows)):]
+	}
+	return rows[:min(q.limit+1, len(rows))], nil
+}
+
+func HandleListUsersByID(fn func() ([]User, error)) FakeHandler {
+	return FakeHandler{
+		Name: "ListUsersByID",
+		Apply: func(q Query) error {
+			results, err := fn()
+			if err != nil {
+				return err
+			}
+			if page, ok := q.(*listUsersByIDPageQuery); ok {
+				if results, err = page.slice(results); err != nil {
+					return err
+				}
+			}
+			q.(interface{ SetResults([]User) }).SetResults(results)
+			return nil
+		},
+	}
+}
+func ExpectListUsersByID(results []User, err error) Step {
+	return Step{
+		SQL:  listUsersByID,
+		Args: nil,
+		Apply: func(q Query) error {
+			q.(interface{ SetResults([]User) }).SetResults(results)
+			return err
+		},
+	}
+}
+
+// ExpectListUsersByIDPage expects EvalPage to read the page following
+// cursor. results are the rows read from the database, up to limit+1 of them:
+// the extra row makes EvalPage report HasMore. It panics when cursor is
+// invalid.
+func ExpectListUsersByIDPage(cursor string, limit int, results []User, err error) Step {
+	page, cursorErr := newListUsersByIDPageQuery(&ListUsersByIDQuery{}, cursor, limit)
+	if cursorErr != nil {
+		panic(cursorErr)
+	}
+	return Step{
+		SQL:  page.SQL(),
+		Args: page.Args(),
+		Apply: func(q Query) error {
+			q.(interface{ SetResults([]User) }).SetResults(results)
+			return err
+		},
+	}
+}
+
+const listUsersByIDs = `-- name: ListUsersByIDs :many
+SELECT id, name, email, created_at FROM users
+WHERE id IN (/*SLICE:ids*/?)
+ORDER BY id
+`
+
+type ListUsersByIDsQuery struct {
+	ex      QueryExecutor
+	ids     []int64
+	results []User
+}
+
+func (q *ListUsersByIDsQuery) SQL() string {
+	query := listUsersByIDs
+	if len(q.ids) > 0 {
+		query = strings.Replace(query, "/*SLICE:ids*/?", strings.Repeat(",?", len(q.ids))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:ids*/?", "NULL", 1)
+	}
+	return query
+}
+
+func (q *ListUsersByIDsQuery) QueryName() string {
+	return "ListUsersByIDs"
+}
+
+func (q *ListUsersByIDsQuery) QueryCmd() string {
+	return ":many"
+}
+
+func (q *ListUsersByIDsQuery) SourceFile() string {
+	return "query.sql"
+}
+
+// HasSqlcSlices keeps the query out of the prepared statement cache, its SQL
+// depends on the length of its sqlc.slice arguments
+func (q *ListUsersByIDsQuery) HasSqlcSlices() bool {
+	return true
+}
+
+func (q *ListUsersByIDsQuery) Args() []any {
+	var args []any
+	for _, v := range q.ids {
+		args = append(args, v)
+	}
+	return args
+}
+
+// LogArgs returns Args with the values of columns missing from log_args_allowlist redacted
+func (q *ListUsersByIDsQuery) LogArgs() []any {
+	var args []any
+	for _, v := range q.ids {
+		args = append(args, v)
+	}
+	return args
+}
+
+func (q *ListUsersByIDsQuery) ScanRow(row *sql.Rows) error {
+	var i User
+	if err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.CreatedAt,
+	); err != nil {
+		return err
+	}
+	q.results = append(q.results, i)
+	return nil
+}
+
+func (q *ListUsersByIDsQuery) Results() []User {
+	return q.results
+}
+
+func (q *ListUsersByIDsQuery) SetResults(results []User) {
+	q.results = results
+}
+func (q *ListUsersByIDsQuery) Eval(ctx context.Context, ids []int64) ([]User, error) {
+	q.ids = ids
+	q.results = nil
+	if err := q.ex.Execute(ctx, q); err != nil {
+		return nil, err
+	}
+	return q.Results(), nil
+}
+
+// listUsersByIDsStream hands ListUsersByIDs rows to an Iter loop one at a time.
+type listUsersByIDsStream struct {
+	*ListUsersByIDsQuery
+	yield   func(User, error) bool
+	stopped bool
+}
+
+func (s *listUsersByIDsStream) ScanNext(row *sql.Rows) (bool, error) {
+	var i User
+	if err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.CreatedAt,
+	); err != nil {
+		return false, err
+	}
+	s.stopped = !s.yield(i, nil)
+	return !s.stopped, nil
+}
+
+// SetResults yields results provided by a StubExecutor step.
+func (s *listUsersByIDsStream) SetResults(results []User) {
+	for _, r := range results {
+		if s.stopped = !s.yield(r, nil); s.stopped {
+			return
+		}
+	}
+}
+
+// Iter executes the query and yields rows as they are scanned. The rows are
+// closed when the loop finishes or breaks early.
+func (q *ListUsersByIDsQuery) Iter(ctx context.Context, ids []int64) iter.Seq2[User, error] {
+	return func(yield func(User, error) bool) {
+		q.ids = ids
+		s := &listUsersByIDsStream{ListUsersByIDsQuery: q, yield: yield}
+		if err := q.ex.Execute(ctx, s); err != nil && !s.stopped {
+			var zero User
+			yield(zero, err)
+		}
+	}
+}
+
+func NewListUsersByIDsQuery(ex QueryExecutor) *ListUsersByIDsQuery {
+	return &ListUsersByIDsQuery{ex: ex}
+}
+
+func HandleListUsersByIDs(fn func(ids []int64) ([]User, error)) FakeHandler {
+	return FakeHandler{
+		Name: "ListUsersByIDs",
+		Apply: func(q Query) error {
+			var query *ListUsersByIDsQuery
+			switch x := q.(type) {
+			case *ListUsersByIDsQuery:
+				query = x
+			case *listUsersByIDsStream:
+				query = x.ListUsersByIDsQuery
+			}
+			results, err := fn(query.ids)
+			if err != nil {
+				return err
+			}
+			q.(interface{ SetResults([]User) }).SetResults(results)
+			return nil
+		},
+	}
+}
+func ExpectListUsersByIDs(ids []int64, results []User, err error) Step {
+	expanded := &ListUsersByIDsQuery{ids: ids}
+	return Step{
+		SQL:  expanded.SQL(),
+		Args: expanded.Args(),
+		Apply: func(q Query) error {
+			q.(interface{ SetResults([]User) }).SetResults(results)
 			return err
 		},
 	}
@@ -557,10 +1583,27 @@ func (q *UpdateUserEmailQuery) SQL() string {
 	return updateUserEmail
 }
 
+func (q *UpdateUserEmailQuery) QueryName() string {
+	return "UpdateUserEmail"
+}
+
+func (q *UpdateUserEmailQuery) QueryCmd() string {
+	return ":execrows"
+}
+
+func (q *UpdateUserEmailQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *UpdateUserEmailQuery) Args() []any {
 	return []any{q.email, q.iD}
 }
 
+// LogArgs returns Args with the values of columns missing from log_args_allowlist redacted
+func (q *UpdateUserEmailQuery) LogArgs() []any {
+	return []any{RedactedArg, q.iD}
+}
+
 func (q *UpdateUserEmailQuery) SetRowsAffected(n int64) {
 	q.rowsAffected = n
 }
@@ -576,6 +1619,21 @@ func (q *UpdateUserEmailQuery) Eval(ctx context.Context, email string, iD int64)
 func NewUpdateUserEmailQuery(ex QueryExecutor) *UpdateUserEmailQuery {
 	return &UpdateUserEmailQuery{ex: ex}
 }
+
+func HandleUpdateUserEmail(fn func(email string, iD int64) (int64, error)) FakeHandler {
+	return FakeHandler{
+		Name: "UpdateUserEmail",
+		Apply: func(q Query) error {
+			query := q.(*UpdateUserEmailQuery)
+			rowsAffected, err := fn(query.email, query.iD)
+			if err != nil {
+				return err
+			}
+			query.SetRowsAffected(rowsAffected)
+			return nil
+		},
+	}
+}
 func ExpectUpdateUserEmail(email string, iD int64, rowsAffected int64, err error) Step {
 	return Step{
 		SQL:  updateUserEmail,
@@ -587,6 +1645,17 @@ func ExpectUpdateUserEmail(email string, iD int64, rowsAffected int64, err error
 	}
 }
 
+func ExpectUpdateUserEmailMatching(email, iD ArgMatcher, rowsAffected int64, err error) Step {
+	return Step{
+		SQL:  updateUserEmail,
+		Args: []any{email, iD},
+		Apply: func(q Query) error {
+			q.(*UpdateUserEmailQuery).SetRowsAffected(rowsAffected)
+			return err
+		},
+	}
+}
+
 const updateUserName = `-- name: UpdateUserName :execresult
 UPDATE users
 SET name = ?
@@ -603,10 +1672,27 @@ func (q *UpdateUserNameQuery) SQL() string {
 	return updateUserName
 }
 
+func (q *UpdateUserNameQuery) QueryName() string {
+	return "UpdateUserName"
+}
+
+func (q *UpdateUserNameQuery) QueryCmd() string {
+	return ":execresult"
+}
+
+func (q *UpdateUserNameQuery) SourceFile() string {
+	return "query.sql"
+}
+
 func (q *UpdateUserNameQuery) Args() []any {
 	return []any{q.name, q.iD}
 }
 
+// LogArgs returns Args with the values of columns missing from log_args_allowlist redacted
+func (q *UpdateUserNameQuery) LogArgs() []any {
+	return []any{q.name, q.iD}
+}
+
 func NewUpdateUserNameQuery(ex QueryExecutor) *UpdateUserNameQuery {
 	return &UpdateUserNameQuery{ex: ex}
 }
@@ -1,14 +1,63 @@
 package db_test
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/sqlc-dev/sqlc-gen-go/examples/sqlite/db"
 	_ "modernc.org/sqlite"
 )
 
+// serializationError mimics a driver error carrying SQLSTATE 40001
+type serializationError struct{}
+
+func (serializationError) Error() string    { return "could not serialize access" }
+func (serializationError) SQLState() string { return "40001" }
+
+// recordingTracer records the name and row count of every traced query
+type recordingTracer struct {
+	started []string
+	rows    map[string]int64
+}
+
+func (r *recordingTracer) TraceQueryStart(ctx context.Context, query db.Query) context.Context {
+	r.started = append(r.started, query.QueryName())
+	return ctx
+}
+
+func (r *recordingTracer) TraceQueryEnd(ctx context.Context, query db.Query, err error, rowsAffected int64) {
+	if r.rows == nil {
+		r.rows = map[string]int64{}
+	}
+	r.rows[query.QueryName()] = rowsAffected
+}
+
+// recordingDB records the statements sent to the database
+type recordingDB struct {
+	*sql.DB
+	statements []string
+	prepared   []string
+}
+
+func (r *recordingDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	r.prepared = append(r.prepared, query)
+	return r.DB.PrepareContext(ctx, query)
+}
+
+func (r *recordingDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	r.statements = append(r.statements, query)
+	return r.DB.QueryRowContext(ctx, query, args...)
+}
+
 func setupTestDB(t *testing.T) *sql.DB {
 	t.Helper()
 
@@ -338,4 +387,712 @@ func TestQueries(t *testing.T) {
 			}
 		}
 	})
+
+	// Middleware wraps every Execute call, including those made inside WithTx,
+	// and sees the deadline of queries annotated with -- @timeout
+	t.Run("Middleware", func(t *testing.T) {
+		var calls []string
+		deadlines := map[string]bool{}
+		record := func(next db.ExecuteFunc) db.ExecuteFunc {
+			return func(ctx context.Context, q db.Query) error {
+				calls = append(calls, q.SQL())
+				_, deadlines[q.QueryName()] = ctx.Deadline()
+				return next(ctx, q)
+			}
+		}
+		mwExecutor := db.NewExecutor(database, db.WithMiddleware(record))
+
+		if _, err := db.NewCountUsersQuery(mwExecutor).Eval(ctx); err != nil {
+			t.Fatalf("CountUsers failed: %v", err)
+		}
+		err := mwExecutor.WithTx(ctx, func(txExecutor db.QueryExecutor) error {
+			_, err := db.NewListUsersQuery(txExecutor).Eval(ctx)
+			return err
+		})
+		if err != nil {
+			t.Fatalf("WithTx failed: %v", err)
+		}
+		if len(calls) != 2 {
+			t.Errorf("expected 2 middleware calls, got %d", len(calls))
+		}
+
+		// ListUsers is annotated with -- @timeout 5s
+		if !deadlines["ListUsers"] {
+			t.Error("expected ListUsers to run with a deadline")
+		}
+		if deadlines["CountUsers"] {
+			t.Error("expected CountUsers to run without a deadline")
+		}
+		if got := (&db.ListUsersQuery{}).Timeout(); got != 5*time.Second {
+			t.Errorf("expected 5s timeout, got %v", got)
+		}
+	})
+
+	// Iter streams rows one at a time and stops scanning when the loop breaks
+	t.Run("Iter", func(t *testing.T) {
+		var count int
+		for user, err := range db.NewListUsersQuery(executor).Iter(ctx) {
+			if err != nil {
+				t.Fatalf("ListUsers iteration failed: %v", err)
+			}
+			if user.ID == 0 {
+				t.Errorf("user not hydrated: %+v", user)
+			}
+			count++
+			if count == 2 {
+				break
+			}
+		}
+		if count != 2 {
+			t.Errorf("expected to stop after 2 users, got %d", count)
+		}
+
+		// The connection must be usable again after breaking out early
+		if _, err := db.NewCountUsersQuery(executor).Eval(ctx); err != nil {
+			t.Fatalf("CountUsers after Iter failed: %v", err)
+		}
+	})
+
+	// RetryPolicy re-runs the whole transaction when it fails with a retryable error
+	t.Run("RetryPolicy", func(t *testing.T) {
+		var retries []int
+		retryExecutor := db.NewExecutor(database, db.WithRetryPolicy(db.RetryPolicy{
+			MaxAttempts: 3,
+			Backoff: func(retry int) time.Duration {
+				retries = append(retries, retry)
+				return time.Millisecond
+			},
+		}))
+
+		attempts := 0
+		err := retryExecutor.WithTx(ctx, func(txExecutor db.QueryExecutor) error {
+			attempts++
+			if _, err := db.NewCountUsersQuery(txExecutor).Eval(ctx); err != nil {
+				return err
+			}
+			if attempts < 3 {
+				return serializationError{}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("WithTx failed: %v", err)
+		}
+		if attempts != 3 || len(retries) != 2 {
+			t.Errorf("expected 3 attempts and 2 backoffs, got %d and %v", attempts, retries)
+		}
+
+		attempts = 0
+		err = retryExecutor.WithTx(ctx, func(txExecutor db.QueryExecutor) error {
+			attempts++
+			return serializationError{}
+		})
+		if !db.IsRetryable(err) {
+			t.Errorf("expected retryable error, got %v", err)
+		}
+		if attempts != 3 {
+			t.Errorf("expected 3 attempts, got %d", attempts)
+		}
+	})
+
+	// Nested WithTx calls run within savepoints of the outer transaction
+	t.Run("NestedTx", func(t *testing.T) {
+		before, err := db.NewCountUsersQuery(executor).Eval(ctx)
+		if err != nil {
+			t.Fatalf("CountUsers failed: %v", err)
+		}
+
+		errInner := errors.New("inner failed")
+		err = executor.WithTx(ctx, func(txExecutor db.QueryExecutor) error {
+			if _, err := db.NewCreateUserQuery(txExecutor).Eval(ctx, "nested_outer", "nested_outer@example.com"); err != nil {
+				return err
+			}
+			err := txExecutor.WithTx(ctx, func(spExecutor db.QueryExecutor) error {
+				if _, err := db.NewCreateUserQuery(spExecutor).Eval(ctx, "nested_rolled_back", "nested_rolled_back@example.com"); err != nil {
+					return err
+				}
+				return errInner
+			})
+			if !errors.Is(err, errInner) {
+				t.Errorf("expected inner error, got %v", err)
+			}
+			return txExecutor.WithTx(ctx, func(spExecutor db.QueryExecutor) error {
+				_, err := db.NewCreateUserQuery(spExecutor).Eval(ctx, "nested_released", "nested_released@example.com")
+				return err
+			})
+		})
+		if err != nil {
+			t.Fatalf("WithTx failed: %v", err)
+		}
+
+		after, err := db.NewCountUsersQuery(executor).Eval(ctx)
+		if err != nil {
+			t.Fatalf("CountUsers failed: %v", err)
+		}
+		if after != before+2 {
+			t.Errorf("expected %d users after nested transactions, got %d", before+2, after)
+		}
+	})
+
+	// sqlc.slice expands into one placeholder per element
+	t.Run("SqlcSlice", func(t *testing.T) {
+		var ids []int64
+		for _, name := range []string{"slice_a", "slice_b", "slice_c"} {
+			user, err := db.NewCreateUserQuery(executor).Eval(ctx, name, name+"@example.com")
+			if err != nil {
+				t.Fatalf("CreateUser failed: %v", err)
+			}
+			ids = append(ids, user.ID)
+		}
+
+		users, err := db.NewListUsersByIDsQuery(executor).Eval(ctx, ids[:2])
+		if err != nil {
+			t.Fatalf("ListUsersByIDs failed: %v", err)
+		}
+		if len(users) != 2 || users[0].ID != ids[0] || users[1].ID != ids[1] {
+			t.Errorf("expected users %v, got %+v", ids[:2], users)
+		}
+
+		users, err = db.NewListUsersByIDsQuery(executor).Eval(ctx, nil)
+		if err != nil {
+			t.Fatalf("ListUsersByIDs with no ids failed: %v", err)
+		}
+		if len(users) != 0 {
+			t.Errorf("expected no users, got %d", len(users))
+		}
+
+		post, err := db.NewCreatePostQuery(executor).Eval(ctx, db.CreatePostParams{AuthorID: ids[2], Title: "slice", Body: "body"})
+		if err != nil {
+			t.Fatalf("CreatePost failed: %v", err)
+		}
+		n, err := db.NewDeleteAuthorPostsQuery(executor).Eval(ctx, ids[2], []int64{post.ID, post.ID + 1000})
+		if err != nil {
+			t.Fatalf("DeleteAuthorPosts failed: %v", err)
+		}
+		if n != 1 {
+			t.Errorf("expected 1 deleted post, got %d", n)
+		}
+
+		stub := db.NewStubExecutor(t, db.ExpectListUsersByIDs([]int64{1, 2}, []db.User{{ID: 1}, {ID: 2}}, nil))
+		if _, err := db.NewListUsersByIDsQuery(stub).Eval(ctx, []int64{1, 2}); err != nil {
+			t.Fatalf("stubbed ListUsersByIDs failed: %v", err)
+		}
+		stub.AssertDone()
+	})
+
+	// The Querier facade delegates to the query structs for upstream-style callers
+	t.Run("QuerierFacade", func(t *testing.T) {
+		var querier db.Querier = db.New(database)
+
+		created, err := querier.CreateUser(ctx, "facade", "facade@example.com")
+		if err != nil {
+			t.Fatalf("CreateUser failed: %v", err)
+		}
+		user, err := querier.GetUser(ctx, created.ID)
+		if err != nil {
+			t.Fatalf("GetUser failed: %v", err)
+		}
+		if user.Email != "facade@example.com" {
+			t.Errorf("expected facade@example.com, got %s", user.Email)
+		}
+
+		// WithTx keeps the options of the executor, like its middleware
+		var calls []string
+		queries := db.NewQueries(db.NewExecutor(database, db.WithMiddleware(func(next db.ExecuteFunc) db.ExecuteFunc {
+			return func(ctx context.Context, q db.Query) error {
+				calls = append(calls, q.QueryName())
+				return next(ctx, q)
+			}
+		})))
+		tx, err := database.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatalf("BeginTx failed: %v", err)
+		}
+		if err := queries.WithTx(tx).DeleteUser(ctx, created.ID); err != nil {
+			t.Fatalf("DeleteUser failed: %v", err)
+		}
+		if err := tx.Rollback(); err != nil {
+			t.Fatalf("Rollback failed: %v", err)
+		}
+		if _, err := querier.GetUser(ctx, created.ID); err != nil {
+			t.Errorf("expected user to survive rolled back delete: %v", err)
+		}
+		if len(calls) != 1 || calls[0] != "DeleteUser" {
+			t.Errorf("expected DeleteUser to run through the middleware, got %v", calls)
+		}
+	})
+
+	// RoutingExecutor reads from replicas and writes to the primary
+	t.Run("RoutingExecutor", func(t *testing.T) {
+		replica := setupTestDB(t)
+		defer replica.Close()
+		router := db.NewRoutingExecutor(database, []db.DBTX{replica})
+
+		created, err := db.NewCreateUserQuery(router).Eval(ctx, "routed", "routed@example.com")
+		if err != nil {
+			t.Fatalf("CreateUser failed: %v", err)
+		}
+
+		// The replica is empty, so reads routed to it do not see the new user
+		if _, err := db.NewGetUserQuery(router).Eval(ctx, created.ID); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("expected GetUser to run on the replica, got err=%v", err)
+		}
+
+		// CountUsers is annotated with -- @primary
+		count, err := db.NewCountUsersQuery(router).Eval(ctx)
+		if err != nil {
+			t.Fatalf("CountUsers failed: %v", err)
+		}
+		if count == 0 {
+			t.Error("expected CountUsers to run on the primary")
+		}
+
+		err = router.WithTx(ctx, func(tx db.QueryExecutor) error {
+			_, err := db.NewGetUserQuery(tx).Eval(ctx, created.ID)
+			return err
+		})
+		if err != nil {
+			t.Errorf("expected GetUser inside WithTx to run on the primary: %v", err)
+		}
+	})
+
+	// QueryTracer is called around every query, including those inside WithTx
+	t.Run("Tracer", func(t *testing.T) {
+		tracer := &recordingTracer{}
+		traced := db.NewExecutor(database, db.WithTracer(tracer))
+
+		created, err := db.NewCreateUserQuery(traced).Eval(ctx, "traced", "traced@example.com")
+		if err != nil {
+			t.Fatalf("CreateUser failed: %v", err)
+		}
+		users, err := db.NewListUsersQuery(traced).Eval(ctx)
+		if err != nil {
+			t.Fatalf("ListUsers failed: %v", err)
+		}
+		err = traced.WithTx(ctx, func(tx db.QueryExecutor) error {
+			_, err := db.NewUpdateUserEmailQuery(tx).Eval(ctx, "traced2@example.com", created.ID)
+			return err
+		})
+		if err != nil {
+			t.Fatalf("WithTx failed: %v", err)
+		}
+
+		if len(tracer.started) != 3 {
+			t.Errorf("expected 3 traced queries, got %v", tracer.started)
+		}
+		if n := tracer.rows["CreateUser"]; n != 1 {
+			t.Errorf("expected CreateUser to report 1 row, got %d", n)
+		}
+		if n := tracer.rows["ListUsers"]; n != int64(len(users)) {
+			t.Errorf("expected ListUsers to report %d rows, got %d", len(users), n)
+		}
+		if n := tracer.rows["UpdateUserEmail"]; n != 1 {
+			t.Errorf("expected UpdateUserEmail to report 1 row, got %d", n)
+		}
+	})
+
+	// WithSQLComments tags statements without changing Query.SQL
+	t.Run("SQLComments", func(t *testing.T) {
+		recorder := &recordingDB{DB: database}
+		tagged := db.NewExecutor(recorder, db.WithSQLComments(map[string]string{"application": "users-api"}))
+		tagCtx := db.ContextWithSQLCommentTags(ctx, map[string]string{"route": "/users/{id}"})
+
+		query := db.NewCountUsersQuery(tagged)
+		if _, err := query.Eval(tagCtx); err != nil {
+			t.Fatalf("CountUsers failed: %v", err)
+		}
+		if len(recorder.statements) != 1 {
+			t.Fatalf("expected 1 statement, got %d", len(recorder.statements))
+		}
+		want := strings.TrimSpace(query.SQL()) + " /*application='users-api',file='query.sql',query='CountUsers',route='%2Fusers%2F%7Bid%7D'*/"
+		if recorder.statements[0] != want {
+			t.Errorf("unexpected statement:\nwant: %q\ngot:  %q", want, recorder.statements[0])
+		}
+		if strings.Contains(query.SQL(), "/*") {
+			t.Errorf("Query.SQL must not include the comment: %q", query.SQL())
+		}
+
+		// the tags make the SQL text change from call to call, so the query runs unprepared
+		recorder.statements = nil
+		commented := db.NewExecutor(recorder, db.WithPreparedStatements(), db.WithSQLComments(nil))
+		for _, route := range []string{"/a", "/b"} {
+			tagged := db.ContextWithSQLCommentTags(ctx, map[string]string{"route": route})
+			if _, err := db.NewCountUsersQuery(commented).Eval(tagged); err != nil {
+				t.Fatalf("CountUsers failed: %v", err)
+			}
+		}
+		if len(recorder.prepared) != 0 || len(recorder.statements) != 2 {
+			t.Errorf("expected queries with context tags to run unprepared, got %v", recorder.statements)
+		}
+	})
+
+	// Prepare prepares every query up front and reuses the statements, also inside WithTx
+	t.Run("PreparedStatements", func(t *testing.T) {
+		recorder := &recordingDB{DB: database}
+		prepared, err := db.Prepare(ctx, recorder)
+		if err != nil {
+			t.Fatalf("Prepare failed: %v", err)
+		}
+		preparedCount := len(recorder.prepared)
+
+		created, err := db.NewCreateUserQuery(prepared).Eval(ctx, "prepared", "prepared@example.com")
+		if err != nil {
+			t.Fatalf("CreateUser failed: %v", err)
+		}
+		err = prepared.WithTx(ctx, func(tx db.QueryExecutor) error {
+			if _, err := db.NewUpdateUserEmailQuery(tx).Eval(ctx, "prepared2@example.com", created.ID); err != nil {
+				return err
+			}
+			user, err := db.NewGetUserQuery(tx).Eval(ctx, created.ID)
+			if err != nil {
+				return err
+			}
+			if user.Email != "prepared2@example.com" {
+				t.Errorf("expected prepared2@example.com, got %s", user.Email)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("WithTx failed: %v", err)
+		}
+
+		if len(recorder.prepared) != preparedCount {
+			t.Errorf("expected prepared statements to be reused, got %d new prepares", len(recorder.prepared)-preparedCount)
+		}
+		if len(recorder.statements) != 0 {
+			t.Errorf("expected no unprepared statements, got %v", recorder.statements)
+		}
+
+		// queries whose SQL text changes from call to call run unprepared
+		for _, ids := range [][]int64{{created.ID}, {created.ID, created.ID + 1}} {
+			if _, err := db.NewListUsersByIDsQuery(prepared).Eval(ctx, ids); err != nil {
+				t.Fatalf("ListUsersByIDs failed: %v", err)
+			}
+		}
+		if len(recorder.prepared) != preparedCount {
+			t.Errorf("expected sqlc.slice queries not to be prepared, got %v", recorder.prepared[preparedCount:])
+		}
+
+		closedDB := setupTestDB(t)
+		closedDB.Close()
+		broken := db.NewExecutor(closedDB, db.WithPreparedStatements())
+		if _, err := db.NewGetUserQuery(broken).Eval(ctx, created.ID); err == nil || !strings.HasPrefix(err.Error(), "prepare GetUser: ") {
+			t.Errorf("expected the prepare error, got %v", err)
+		}
+
+		// Close releases the statements, later queries prepare them again
+		if err := prepared.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		if _, err := db.NewGetUserQuery(prepared).Eval(ctx, created.ID); err != nil {
+			t.Fatalf("GetUser after Close failed: %v", err)
+		}
+		if len(recorder.prepared) != preparedCount+1 {
+			t.Errorf("expected GetUser to be prepared again after Close")
+		}
+	})
+
+	// LoggingExecutor logs queries with slog and redacts arguments outside log_args_allowlist
+	t.Run("LoggingExecutor", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		logged := db.NewLoggingExecutor(db.NewExecutor(database), logger, db.WithSlowThreshold(time.Hour))
+
+		var entries []map[string]any
+		readEntries := func() {
+			entries = nil
+			for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+				var entry map[string]any
+				if err := json.Unmarshal(line, &entry); err != nil {
+					t.Fatalf("invalid log line %q: %v", line, err)
+				}
+				entries = append(entries, entry)
+			}
+			buf.Reset()
+		}
+
+		created, err := db.NewCreateUserQuery(logged).Eval(ctx, "logged", "logged@example.com")
+		if err != nil {
+			t.Fatalf("CreateUser failed: %v", err)
+		}
+		readEntries()
+		if len(entries) != 1 {
+			t.Fatalf("expected 1 log entry, got %d", len(entries))
+		}
+		entry := entries[0]
+		if entry["level"] != "DEBUG" || entry["query"] != "CreateUser" || entry["cmd"] != ":one" {
+			t.Errorf("unexpected entry: %v", entry)
+		}
+		if entry["rows_affected"] != float64(1) {
+			t.Errorf("expected rows_affected 1, got %v", entry["rows_affected"])
+		}
+		args, _ := entry["args"].([]any)
+		if len(args) != 2 || args[0] != "logged" || args[1] != db.RedactedArg {
+			t.Errorf("expected email to be redacted, got %v", entry["args"])
+		}
+
+		// sqlc.slice arguments are logged per element, like Args expands them
+		if _, err := db.NewListUsersByIDsQuery(logged).Eval(ctx, []int64{created.ID, created.ID + 1000}); err != nil {
+			t.Fatalf("ListUsersByIDs failed: %v", err)
+		}
+		readEntries()
+		args, _ = entries[0]["args"].([]any)
+		if len(args) != 2 || args[0] != float64(created.ID) || args[1] != float64(created.ID+1000) {
+			t.Errorf("expected one logged arg per ID, got %v", entries[0]["args"])
+		}
+
+		err = logged.WithTx(ctx, func(tx db.QueryExecutor) error {
+			_, err := db.NewGetUserQuery(tx).Eval(ctx, created.ID+1000)
+			return err
+		})
+		if !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("expected sql.ErrNoRows, got %v", err)
+		}
+		readEntries()
+		if len(entries) != 1 || entries[0]["level"] != "ERROR" || entries[0]["query"] != "GetUser" {
+			t.Errorf("expected failed GetUser inside WithTx to be logged at error level, got %v", entries)
+		}
+
+		slow := db.NewLoggingExecutor(db.NewExecutor(database), logger, db.WithSlowThreshold(time.Nanosecond))
+		if _, err := db.NewCountUsersQuery(slow).Eval(ctx); err != nil {
+			t.Fatalf("CountUsers failed: %v", err)
+		}
+		readEntries()
+		if len(entries) != 1 || entries[0]["level"] != "WARN" || entries[0]["msg"] != "slow query" {
+			t.Errorf("expected slow query warning, got %v", entries)
+		}
+	})
+
+	t.Run("SensitiveColumns", func(t *testing.T) {
+		user := db.User{ID: 1, Name: "secret", Email: "secret@example.com"}
+		row := db.GetPostWithAuthorRow{User: user}
+
+		var buf bytes.Buffer
+		slog.New(slog.NewJSONHandler(&buf, nil)).Info("user", "user", user, "row", row)
+
+		for name, out := range map[string]string{
+			"String":   fmt.Sprint(user),
+			"GoString": fmt.Sprintf("%#v", user),
+			"row":      fmt.Sprintf("%v", row),
+			"slog":     buf.String(),
+		} {
+			if strings.Contains(out, user.Email) {
+				t.Errorf("%s leaks email: %s", name, out)
+			}
+			if !strings.Contains(out, "[REDACTED]") || !strings.Contains(out, user.Name) {
+				t.Errorf("%s: expected masked email next to name, got %s", name, out)
+			}
+		}
+	})
+
+	t.Run("RecordAndReplay", func(t *testing.T) {
+		recorder := db.NewRecordingExecutor(db.NewExecutor(database))
+
+		var created db.User
+		err := recorder.WithTx(ctx, func(tx db.QueryExecutor) error {
+			var err error
+			created, err = db.NewCreateUserQuery(tx).Eval(ctx, "recorded", "recorded@example.com")
+			return err
+		})
+		if err != nil {
+			t.Fatalf("CreateUser failed: %v", err)
+		}
+		users, err := db.NewListUsersQuery(recorder).Eval(ctx)
+		if err != nil {
+			t.Fatalf("ListUsers failed: %v", err)
+		}
+		affected, err := db.NewUpdateUserEmailQuery(recorder).Eval(ctx, "replayed@example.com", created.ID)
+		if err != nil {
+			t.Fatalf("UpdateUserEmail failed: %v", err)
+		}
+		if _, err := db.NewGetUserQuery(recorder).Eval(ctx, -1); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("expected sql.ErrNoRows, got %v", err)
+		}
+
+		path := filepath.Join(t.TempDir(), "fixture.json")
+		if err := recorder.WriteFixture(path); err != nil {
+			t.Fatalf("WriteFixture failed: %v", err)
+		}
+		entries, err := db.ReadFixture(path)
+		if err != nil {
+			t.Fatalf("ReadFixture failed: %v", err)
+		}
+		if len(entries) != 4 || entries[0].Name != "CreateUser" {
+			t.Fatalf("unexpected entries: %+v", entries)
+		}
+
+		replay := db.NewReplayExecutor(entries)
+		var replayed db.User
+		err = replay.WithTx(ctx, func(tx db.QueryExecutor) error {
+			var err error
+			replayed, err = db.NewCreateUserQuery(tx).Eval(ctx, "recorded", "recorded@example.com")
+			return err
+		})
+		if err != nil {
+			t.Fatalf("replaying CreateUser failed: %v", err)
+		}
+		if replayed.ID != created.ID || replayed.Name != created.Name || !replayed.CreatedAt.Equal(created.CreatedAt) {
+			t.Errorf("expected %+v, got %+v", created, replayed)
+		}
+		replayedUsers, err := db.NewListUsersQuery(replay).Eval(ctx)
+		if err != nil || len(replayedUsers) != len(users) {
+			t.Fatalf("expected %d users, got %d, %v", len(users), len(replayedUsers), err)
+		}
+		if n, err := db.NewUpdateUserEmailQuery(replay).Eval(ctx, "replayed@example.com", created.ID); err != nil || n != affected {
+			t.Errorf("expected %d rows affected, got %d, %v", affected, n, err)
+		}
+		if _, err := db.NewGetUserQuery(replay).Eval(ctx, -2); err == nil || !strings.Contains(err.Error(), "differs from fixture") {
+			t.Errorf("expected different arguments to fail, got %v", err)
+		}
+		if replay.Remaining() != 0 {
+			t.Errorf("expected all entries to be replayed, %d left", replay.Remaining())
+		}
+	})
+
+	t.Run("FakeExecutor", func(t *testing.T) {
+		fake := db.NewFakeExecutor(t)
+		users := db.TableOf[db.User](fake)
+		posts := db.TableOf[db.Post](fake)
+		fake.Handle(
+			db.HandleCreateUserGetID(func(name string, email string) (int64, error) {
+				id := users.NextID()
+				users.Insert(db.User{ID: id, Name: name, Email: email})
+				return id, nil
+			}),
+			db.HandleCreatePost(func(arg db.CreatePostParams) (db.Post, error) {
+				post := db.Post{ID: posts.NextID(), AuthorID: arg.AuthorID, Title: arg.Title, Body: arg.Body}
+				posts.Insert(post)
+				return post, nil
+			}),
+		)
+
+		err := fake.WithTx(ctx, func(tx db.QueryExecutor) error {
+			authorID, err := db.NewCreateUserGetIDQuery(tx).Eval(ctx, "fake", "fake@example.com")
+			if err != nil {
+				return err
+			}
+			_, err = db.NewCreatePostQuery(tx).Eval(ctx, db.CreatePostParams{AuthorID: authorID, Title: "first"})
+			return err
+		})
+		if err != nil {
+			t.Fatalf("WithTx failed: %v", err)
+		}
+		if users.Len() != 1 || posts.Len() != 1 || posts.Rows()[0].AuthorID != users.Rows()[0].ID {
+			t.Errorf("unexpected tables: %+v, %+v", users.Rows(), posts.Rows())
+		}
+
+		err = fake.WithTx(ctx, func(tx db.QueryExecutor) error {
+			if _, err := db.NewCreatePostQuery(tx).Eval(ctx, db.CreatePostParams{AuthorID: 1, Title: "second"}); err != nil {
+				return err
+			}
+			return sql.ErrTxDone
+		})
+		if !errors.Is(err, sql.ErrTxDone) || posts.Len() != 1 {
+			t.Errorf("expected the post to be rolled back: %v, %d posts", err, posts.Len())
+		}
+	})
+
+	t.Run("Paginate", func(t *testing.T) {
+		all, err := db.NewListUsersByIDQuery(executor).Eval(ctx)
+		if err != nil {
+			t.Fatalf("ListUsersByID failed: %v", err)
+		}
+		for len(all) < 5 {
+			user, err := db.NewCreateUserQuery(executor).Eval(ctx, fmt.Sprintf("page%d", len(all)), fmt.Sprintf("page%d@example.com", len(all)))
+			if err != nil {
+				t.Fatalf("CreateUser failed: %v", err)
+			}
+			all = append(all, user)
+		}
+
+		var paged []db.User
+		cursor := ""
+		for {
+			page, err := db.NewListUsersByIDQuery(executor).EvalPage(ctx, cursor, 2)
+			if err != nil {
+				t.Fatalf("EvalPage failed: %v", err)
+			}
+			if len(page.Items) > 2 {
+				t.Fatalf("expected at most 2 items, got %d", len(page.Items))
+			}
+			paged = append(paged, page.Items...)
+			if !page.HasMore {
+				if page.NextCursor != "" {
+					t.Errorf("expected no cursor on the last page, got %q", page.NextCursor)
+				}
+				break
+			}
+			cursor = page.NextCursor
+		}
+		if len(paged) != len(all) {
+			t.Fatalf("expected %d users, got %d", len(all), len(paged))
+		}
+		for i := range all {
+			if paged[i].ID != all[i].ID {
+				t.Errorf("user %d: expected ID %d, got %d", i, all[i].ID, paged[i].ID)
+			}
+		}
+
+		after, err := db.EncodeListUsersByIDCursor(all[1].ID)
+		if err != nil {
+			t.Fatalf("EncodeListUsersByIDCursor failed: %v", err)
+		}
+		if id, err := db.DecodeListUsersByIDCursor(after); err != nil || id != all[1].ID {
+			t.Errorf("expected cursor of ID %d, got %d, %v", all[1].ID, id, err)
+		}
+		page, err := db.NewListUsersByIDQuery(executor).EvalPage(ctx, after, 1)
+		if err != nil || len(page.Items) != 1 || page.Items[0].ID != all[2].ID {
+			t.Errorf("expected the page after ID %d to start at %d, got %+v, %v", all[1].ID, all[2].ID, page.Items, err)
+		}
+		if _, err := db.NewListUsersByIDQuery(executor).EvalPage(ctx, "not a cursor", 2); err == nil {
+			t.Error("expected an invalid cursor to fail")
+		}
+
+		// the stub and the fake serve pages the same way
+		rows := []db.User{{ID: 1}, {ID: 2}, {ID: 3}}
+		next, err := db.EncodeListUsersByIDCursor(2)
+		if err != nil {
+			t.Fatalf("EncodeListUsersByIDCursor failed: %v", err)
+		}
+		stub := db.NewStubExecutor(t,
+			db.ExpectListUsersByIDPage("", 2, rows, nil),
+			db.ExpectListUsersByIDPage(next, 2, rows[2:], nil),
+		)
+		fake := db.NewFakeExecutor(t, db.HandleListUsersByID(func() ([]db.User, error) {
+			return rows, nil
+		}))
+		for _, ex := range []db.QueryExecutor{stub, fake} {
+			page, err := db.NewListUsersByIDQuery(ex).EvalPage(ctx, "", 2)
+			if err != nil || len(page.Items) != 2 || page.NextCursor != next {
+				t.Fatalf("expected the first 2 users and a cursor, got %+v, %v", page, err)
+			}
+			page, err = db.NewListUsersByIDQuery(ex).EvalPage(ctx, page.NextCursor, 2)
+			if err != nil || len(page.Items) != 1 || page.Items[0].ID != 3 || page.HasMore {
+				t.Errorf("expected the last user, got %+v, %v", page, err)
+			}
+		}
+		stub.AssertDone()
+	})
+
+	t.Run("ExecuteAll", func(t *testing.T) {
+		users := db.NewListUsersQuery(executor)
+		posts := db.NewListPostsWithAuthorQuery(executor)
+		if err := db.ExecuteAll(ctx, executor, 2, users, posts); err != nil {
+			t.Fatalf("ExecuteAll failed: %v", err)
+		}
+		if len(users.Results()) == 0 {
+			t.Error("expected ListUsers results")
+		}
+
+		// no user has the zero ID
+		missing := db.NewGetUserQuery(executor)
+		if err := db.ExecuteAll(ctx, executor, 2, db.NewListUsersQuery(executor), missing); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("expected sql.ErrNoRows, got %v", err)
+		}
+
+		// CountUsers is annotated with -- @primary
+		if err := db.ExecuteAll(ctx, executor, 0, db.NewCountUsersQuery(executor)); err == nil {
+			t.Error("expected CountUsers to be rejected")
+		}
+	})
 }
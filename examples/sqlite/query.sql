@@ -3,9 +3,15 @@ SELECT * FROM users
 WHERE id = ?;
 
 -- name: ListUsers :many
+-- @timeout 5s
 SELECT * FROM users
 ORDER BY created_at DESC;
 
+-- name: ListUsersByID :many
+-- @paginate keyset(id)
+SELECT * FROM users
+ORDER BY id;
+
 -- name: CreateUser :one
 INSERT INTO users (name, email)
 VALUES (?, ?)
@@ -30,6 +36,7 @@ INSERT INTO users (name, email)
 VALUES (?, ?);
 
 -- name: CountUsers :one
+-- @primary
 SELECT COUNT(*) FROM users;
 
 -- name: CreatePost :one
@@ -48,3 +55,12 @@ SELECT sqlc.embed(posts), sqlc.embed(users)
 FROM posts
 JOIN users ON users.id = posts.author_id
 ORDER BY posts.created_at DESC;
+
+-- name: ListUsersByIDs :many
+SELECT * FROM users
+WHERE id IN (sqlc.slice(ids))
+ORDER BY id;
+
+-- name: DeleteAuthorPosts :execrows
+DELETE FROM posts
+WHERE author_id = ? AND id IN (sqlc.slice(ids));
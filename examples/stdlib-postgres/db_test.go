@@ -3,6 +3,7 @@ package db_test
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"os"
 	"testing"
 	"time"
@@ -395,4 +396,83 @@ func TestQueries(t *testing.T) {
 			}
 		}
 	})
+
+	// Iter streams rows one at a time and stops scanning when the loop breaks
+	t.Run("Iter", func(t *testing.T) {
+		var count int
+		for user, err := range db.NewListUsersQuery(executor).Iter(ctx) {
+			if err != nil {
+				t.Fatalf("ListUsers iteration failed: %v", err)
+			}
+			if user.ID == 0 {
+				t.Errorf("user not hydrated: %+v", user)
+			}
+			count++
+			if count == 2 {
+				break
+			}
+		}
+		if count != 2 {
+			t.Errorf("expected to stop after 2 users, got %d", count)
+		}
+
+		// The connection must be usable again after breaking out early
+		if _, err := db.NewCountUsersQuery(executor).Eval(ctx); err != nil {
+			t.Fatalf("CountUsers after Iter failed: %v", err)
+		}
+	})
+
+	// WithTxOptions starts the transaction with the requested isolation and access mode
+	t.Run("WithTxOptions", func(t *testing.T) {
+		err := executor.WithTxOptions(ctx, db.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true}, func(txExecutor db.QueryExecutor) error {
+			if _, err := db.NewCountUsersQuery(txExecutor).Eval(ctx); err != nil {
+				t.Errorf("CountUsers failed: %v", err)
+				return err
+			}
+			_, err := db.NewCreateUserQuery(txExecutor).Eval(ctx, "readonly", "readonly@example.com")
+			return err
+		})
+		if err == nil {
+			t.Fatal("expected write in read-only transaction to fail")
+		}
+	})
+
+	// Nested WithTx calls run within savepoints of the outer transaction
+	t.Run("NestedTx", func(t *testing.T) {
+		before, err := db.NewCountUsersQuery(executor).Eval(ctx)
+		if err != nil {
+			t.Fatalf("CountUsers failed: %v", err)
+		}
+
+		errInner := errors.New("inner failed")
+		err = executor.WithTx(ctx, func(txExecutor db.QueryExecutor) error {
+			if _, err := db.NewCreateUserQuery(txExecutor).Eval(ctx, "nested_outer", "nested_outer@example.com"); err != nil {
+				return err
+			}
+			err := txExecutor.WithTx(ctx, func(spExecutor db.QueryExecutor) error {
+				if _, err := db.NewCreateUserQuery(spExecutor).Eval(ctx, "nested_rolled_back", "nested_rolled_back@example.com"); err != nil {
+					return err
+				}
+				return errInner
+			})
+			if !errors.Is(err, errInner) {
+				t.Errorf("expected inner error, got %v", err)
+			}
+			return txExecutor.WithTx(ctx, func(spExecutor db.QueryExecutor) error {
+				_, err := db.NewCreateUserQuery(spExecutor).Eval(ctx, "nested_released", "nested_released@example.com")
+				return err
+			})
+		})
+		if err != nil {
+			t.Fatalf("WithTx failed: %v", err)
+		}
+
+		after, err := db.NewCountUsersQuery(executor).Eval(ctx)
+		if err != nil {
+			t.Fatalf("CountUsers failed: %v", err)
+		}
+		if after != before+2 {
+			t.Errorf("expected %d users after nested transactions, got %d", before+2, after)
+		}
+	})
 }
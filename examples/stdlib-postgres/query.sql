@@ -3,6 +3,7 @@ SELECT * FROM users
 WHERE id = $1;
 
 -- name: ListUsers :many
+-- @paginate keyset(created_at, id)
 SELECT * FROM users
 ORDER BY created_at DESC;
 
@@ -12,3 +12,13 @@ func parseDriver(sqlPackage string) opts.SQLDriver {
 		return opts.SQLDriverLibPQ
 	}
 }
+
+// usesMySQLDriver reports whether database/sql code talks to
+// github.com/go-sql-driver/mysql, either because it was picked with
+// sql_driver or because it is the only driver for the mysql engine.
+func usesMySQLDriver(engine string, options *opts.Options) bool {
+	if parseDriver(options.SqlPackage).IsPGX() {
+		return false
+	}
+	return engine == "mysql" || options.SqlDriver == string(opts.SQLDriverGoSQLDriverMySQL)
+}
@@ -19,6 +19,8 @@ type Field struct {
 	Tags    map[string]string
 	Comment string
 	Column  *plugin.Column
+	// Sensitive is set for fields matched by the sensitive_columns option.
+	Sensitive bool
 	// EmbedFields contains the embedded fields that require scanning.
 	EmbedFields []Field
 }
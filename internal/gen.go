@@ -37,20 +37,103 @@ type tmplCtx struct {
 	EmitEnumValidMethod bool
 	EmitAllEnumValues   bool
 	EmitMockExecutor    bool
+	EmitPreparedQueries bool
+	EmitLoggingExecutor bool
+	EmitReplayExecutor  bool
+	EmitFakeExecutor    bool
+	EmitBatchBuilder    bool
+	EmitPipeline        bool
 	UsesCopyFrom        bool
 	UsesBatch           bool
+	UsesMySQLDriver     bool
 	OmitSqlcVersion     bool
 	BuildTags           string
 
 	// Package qualifiers for query struct pattern
 	PackageQualifier       string
 	ModelsPackageQualifier string
+
+	logArgsAllowlist []*opts.ColumnPattern
+	defaultSchema    string
 }
 
 func (t *tmplCtx) OutputQuery(sourceName string) bool {
 	return t.SourceName == sourceName
 }
 
+// LogArg is an argument of a query as logged by LoggingExecutor
+type LogArg struct {
+	// Expr is the Go expression of the argument
+	Expr string
+	// Value is the expression logged in place of the argument, RedactedArg
+	// when the column is missing from log_args_allowlist
+	Value string
+	// Redacted is set when Value hides Expr
+	Redacted bool
+	// Slice is set for sqlc.slice arguments, which Args expands into one
+	// argument per element
+	Slice bool
+}
+
+// LogArgs are the arguments of a query in the order of its Args method
+type LogArgs []LogArg
+
+// HasSlices reports whether any argument is expanded per element
+func (a LogArgs) HasSlices() bool {
+	for _, arg := range a {
+		if arg.Slice {
+			return true
+		}
+	}
+	return false
+}
+
+// LogArgs returns the query arguments logged by LoggingExecutor, with columns
+// missing from log_args_allowlist redacted. It returns nil when no argument
+// may be logged.
+func (t *tmplCtx) LogArgs(q Query) LogArgs {
+	if len(t.logArgsAllowlist) == 0 || q.Arg.isEmpty() {
+		return nil
+	}
+	var args LogArgs
+	allowed := false
+	add := func(expr string, col *plugin.Column) {
+		arg := LogArg{Expr: expr, Value: expr, Slice: col != nil && col.IsSqlcSlice}
+		if opts.MatchesAny(t.logArgsAllowlist, col, t.defaultSchema) {
+			allowed = true
+		} else {
+			arg.Value = t.PackageQualifier + "RedactedArg"
+			arg.Redacted = true
+		}
+		args = append(args, arg)
+	}
+	switch {
+	case q.Arg.HasSqlcSlices() && q.Arg.IsStruct():
+		// mirrors the Args method generated for queries with sqlc.slice
+		for _, f := range q.Arg.Struct.Fields {
+			add("q."+q.Arg.VariableForField(f), f.Column)
+		}
+	case q.Arg.EmitStruct():
+		fields := q.Arg.Struct.Fields
+		if !t.SQLDriver.IsPGX() {
+			fields = q.Arg.UniqueFields()
+		}
+		for _, f := range fields {
+			add("q."+q.Arg.Name+"."+f.Name, f.Column)
+		}
+	case q.Arg.IsStruct():
+		for i, p := range q.Arg.Pairs() {
+			add("q."+p.Name, q.Arg.Struct.Fields[i].Column)
+		}
+	default:
+		add("q."+escape(q.Arg.Name), q.Arg.Column)
+	}
+	if !allowed {
+		return nil
+	}
+	return args
+}
+
 func (t *tmplCtx) codegenQueryMethod(q Query) string {
 	db := "q.db"
 
@@ -141,6 +224,7 @@ func validate(options *opts.Options, enums []Enum, structs []Struct, queries []Q
 func generate(req *plugin.GenerateRequest, options *opts.Options, enums []Enum, structs []Struct, queries []Query) (*plugin.GenerateResponse, error) {
 	i := &importer{
 		Options: options,
+		Engine:  req.Settings.Engine,
 		Queries: queries,
 		Enums:   enums,
 		Structs: structs,
@@ -164,8 +248,13 @@ func generate(req *plugin.GenerateRequest, options *opts.Options, enums []Enum,
 		EmitEnumValidMethod:    options.EmitEnumValidMethod,
 		EmitAllEnumValues:      options.EmitAllEnumValues,
 		EmitMockExecutor:       options.EmitMockExecutor,
+		EmitPreparedQueries:    options.EmitPreparedQueries,
+		EmitLoggingExecutor:    options.EmitLoggingExecutor,
+		EmitReplayExecutor:     options.EmitReplayExecutor,
+		EmitFakeExecutor:       options.EmitFakeExecutor,
 		UsesCopyFrom:           usesCopyFrom(queries),
 		UsesBatch:              usesBatch(queries),
+		UsesMySQLDriver:        usesMySQLDriver(i.Engine, options),
 		SQLDriver:              parseDriver(options.SqlPackage),
 		Q:                      "`",
 		Package:                options.Package,
@@ -176,6 +265,10 @@ func generate(req *plugin.GenerateRequest, options *opts.Options, enums []Enum,
 		OmitSqlcVersion:        options.OmitSqlcVersion,
 		PackageQualifier:       packageQualifier,
 		ModelsPackageQualifier: modelsPackageQualifier,
+		logArgsAllowlist:       options.LogArgsAllowlistColumns,
+	}
+	if req.Catalog != nil {
+		tctx.defaultSchema = req.Catalog.DefaultSchema
 	}
 
 	if tctx.UsesCopyFrom && !tctx.SQLDriver.IsPGX() && options.SqlDriver != string(opts.SQLDriverGoSQLDriverMySQL) {
@@ -193,6 +286,11 @@ func generate(req *plugin.GenerateRequest, options *opts.Options, enums []Enum,
 		return nil, errors.New(":batch* commands are only supported by pgx")
 	}
 
+	// Batch builds on pgx.Batch, database/sql drivers have no equivalent
+	tctx.EmitBatchBuilder = options.EmitBatchBuilder && tctx.SQLDriver.IsPGX()
+	// pgconn.Pipeline is only available in pgx v5
+	tctx.EmitPipeline = options.EmitPipeline && tctx.SQLDriver == opts.SQLDriverPGXV5
+
 	funcMap := template.FuncMap{
 		"lowerTitle": sdk.LowerTitle,
 		"comment":    sdk.DoubleSlashComment,
@@ -255,6 +353,10 @@ func generate(req *plugin.GenerateRequest, options *opts.Options, enums []Enum,
 				name += options.OutputFilesSuffix
 			}
 		}
+		// The querier facade delegates to the query structs, so it lives next to them
+		if templateName == "interfaceFile" && options.OutputQueryFilesDirectory != "" {
+			name = filepath.Join(options.OutputQueryFilesDirectory, name)
+		}
 
 		if !strings.HasSuffix(name, ".go") {
 			name += ".go"
@@ -280,6 +382,10 @@ func generate(req *plugin.GenerateRequest, options *opts.Options, enums []Enum,
 	if options.OutputBatchFileName != "" {
 		batchFileName = options.OutputBatchFileName
 	}
+	querierFileName := "querier.go"
+	if options.OutputQuerierFileName != "" {
+		querierFileName = options.OutputQuerierFileName
+	}
 
 	modelsPackageName := options.Package
 	if options.OutputModelsPackage != "" {
@@ -302,12 +408,18 @@ func generate(req *plugin.GenerateRequest, options *opts.Options, enums []Enum,
 			return nil, err
 		}
 	}
-	if tctx.UsesBatch {
+	if tctx.UsesBatch || tctx.EmitBatchBuilder {
 		if err := execute(batchFileName, options.Package, "batchFile"); err != nil {
 			return nil, err
 		}
 	}
 
+	if options.EmitQuerierFacade {
+		if err := execute(querierFileName, queriesPackageName, "interfaceFile"); err != nil {
+			return nil, err
+		}
+	}
+
 	files := map[string]struct{}{}
 	for _, gq := range queries {
 		files[gq.SourceName] = struct{}{}
@@ -350,6 +462,30 @@ func usesBatch(queries []Query) bool {
 	return false
 }
 
+func usesTimeout(queries []Query) bool {
+	for _, q := range queries {
+		if q.Timeout > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// usesSensitiveFields reports whether an Arg or Ret struct emitted for queries
+// gets masking methods. Arg structs of :copyfrom queries are skipped unless
+// withCopyFromArgs is set, as pgx writes them to the copyfrom file.
+func usesSensitiveFields(queries []Query, withCopyFromArgs bool) bool {
+	for _, q := range queries {
+		if q.Arg.EmitStruct() && (withCopyFromArgs || q.Cmd != metadata.CmdCopyFrom) && q.Arg.Struct.HasSensitiveFields() {
+			return true
+		}
+		if q.Ret.EmitStruct() && q.Ret.Struct.HasSensitiveFields() {
+			return true
+		}
+	}
+	return false
+}
+
 func checkNoTimesForMySQLCopyFrom(queries []Query) error {
 	for _, q := range queries {
 		if q.Cmd != metadata.CmdCopyFrom {
@@ -0,0 +1,80 @@
+package golang
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sqlc-dev/plugin-sdk-go/plugin"
+	"github.com/sqlc-dev/sqlc-gen-go/internal/opts"
+)
+
+func TestTmplCtx_LogArgs(t *testing.T) {
+	options, err := opts.Parse(&plugin.GenerateRequest{
+		PluginOptions: []byte(`{"package": "db", "log_args_allowlist": ["users.name"]}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tctx := &tmplCtx{logArgsAllowlist: options.LogArgsAllowlistColumns, defaultSchema: "public"}
+	users := &plugin.Identifier{Name: "users"}
+	name := Field{Name: "Name", Type: "string", Column: &plugin.Column{Name: "name", Table: users}}
+	ids := Field{Name: "Ids", Type: "[]int64", Column: &plugin.Column{Name: "ids", OriginalName: "id", Table: users, IsSqlcSlice: true}}
+
+	for _, test := range []struct {
+		name string
+		arg  QueryValue
+		want LogArgs
+	}{
+		{
+			name: "slice redacted per element",
+			arg:  QueryValue{Name: "arg", Struct: &Struct{Fields: []Field{name, ids}}},
+			want: LogArgs{
+				{Expr: "q.name", Value: "q.name"},
+				{Expr: "q.ids", Value: "RedactedArg", Redacted: true, Slice: true},
+			},
+		},
+		{
+			name: "slice in params struct",
+			arg:  QueryValue{Emit: true, Name: "arg", Struct: &Struct{Fields: []Field{ids, name}}},
+			want: LogArgs{
+				{Expr: "q.arg.Ids", Value: "RedactedArg", Redacted: true, Slice: true},
+				{Expr: "q.arg.Name", Value: "q.arg.Name"},
+			},
+		},
+		{
+			name: "nothing allowed",
+			arg:  QueryValue{Name: "ids", Typ: "[]int64", Column: ids.Column},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got := tctx.LogArgs(Query{Arg: test.arg})
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("LogArgs() = %+v, want %+v", got, test.want)
+			}
+			if len(got) > 0 && !got.HasSlices() {
+				t.Error("expected HasSlices")
+			}
+		})
+	}
+}
+
+// The go-sql-driver-mysql templates are only picked for :copyfrom packages,
+// so nothing else exercises them; they must stay copies of the stdlib ones.
+func TestGoSQLDriverTemplatesMirrorStdlib(t *testing.T) {
+	for _, name := range []string{"dbCode.tmpl", "queryCode.tmpl"} {
+		std, err := templates.ReadFile("templates/stdlib/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mysql, err := templates.ReadFile("templates/go-sql-driver-mysql/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, stdBody, _ := strings.Cut(string(std), "\n")
+		_, mysqlBody, _ := strings.Cut(string(mysql), "\n")
+		if stdBody != mysqlBody {
+			t.Errorf("templates/go-sql-driver-mysql/%s differs from templates/stdlib/%s below the define line", name, name)
+		}
+	}
+}
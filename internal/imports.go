@@ -59,6 +59,7 @@ func mergeImports(imps ...fileImports) [][]ImportSpec {
 
 type importer struct {
 	Options *opts.Options
+	Engine  string
 	Queries []Query
 	Enums   []Enum
 	Structs []Struct
@@ -119,39 +120,80 @@ func (i *importer) Imports(filename string) [][]ImportSpec {
 }
 
 func (i *importer) dbImports() fileImports {
-	var pkg []ImportSpec
-	std := []ImportSpec{
-		{Path: "context"},
-		{Path: "fmt"},
+	std := map[string]struct{}{
+		"context":     {},
+		"errors":      {},
+		"fmt":         {},
+		"net/url":     {},
+		"sort":        {},
+		"strings":     {},
+		"sync":        {},
+		"sync/atomic": {},
+		"time":        {},
+	}
+	pkg := map[ImportSpec]struct{}{}
+
+	if i.Options.EmitLoggingExecutor {
+		std["log/slog"] = struct{}{}
+	}
+	if i.Options.EmitMockExecutor {
+		// StubExecutor uses reflect and driver to match arguments and sync to serialize calls
+		std["database/sql/driver"] = struct{}{}
+		std["reflect"] = struct{}{}
+		std["sync"] = struct{}{}
+	}
+	if i.Options.EmitFakeExecutor {
+		// FakeExecutor keys its tables by model type
+		std["reflect"] = struct{}{}
+		std["sync"] = struct{}{}
+	}
+	if i.Options.EmitReplayExecutor {
+		// fixtures are stored as JSON files
+		std["bytes"] = struct{}{}
+		std["encoding/json"] = struct{}{}
+		std["os"] = struct{}{}
+		std["sync"] = struct{}{}
 	}
 
 	sqlpkg := parseDriver(i.Options.SqlPackage)
 	switch sqlpkg {
 	case opts.SQLDriverPGXV4:
-		pkg = append(pkg, ImportSpec{Path: "github.com/jackc/pgconn"})
-		pkg = append(pkg, ImportSpec{Path: "github.com/jackc/pgx/v4"})
-		if i.Options.EmitMockExecutor {
-			// reflect is used by StubExecutor for argument comparison
-			std = append(std, ImportSpec{Path: "reflect"})
+		pkg[ImportSpec{Path: "github.com/jackc/pgconn"}] = struct{}{}
+		pkg[ImportSpec{Path: "github.com/jackc/pgx/v4"}] = struct{}{}
+		if i.Options.EmitReplayExecutor {
+			// pgtype decodes the raw values of recorded rows
+			pkg[ImportSpec{Path: "github.com/jackc/pgtype"}] = struct{}{}
 		}
 	case opts.SQLDriverPGXV5:
-		pkg = append(pkg, ImportSpec{Path: "github.com/jackc/pgx/v5/pgconn"})
-		pkg = append(pkg, ImportSpec{Path: "github.com/jackc/pgx/v5"})
-		if i.Options.EmitMockExecutor {
-			// reflect is used by StubExecutor for argument comparison
-			std = append(std, ImportSpec{Path: "reflect"})
+		pkg[ImportSpec{Path: "github.com/jackc/pgx/v5/pgconn"}] = struct{}{}
+		pkg[ImportSpec{Path: "github.com/jackc/pgx/v5"}] = struct{}{}
+		if i.Options.EmitReplayExecutor {
+			// pgtype decodes the raw values of recorded rows
+			pkg[ImportSpec{Path: "github.com/jackc/pgx/v5/pgtype"}] = struct{}{}
+		}
+		if i.Options.EmitPipeline {
+			// Pipeline acquires a connection from the pool and decodes rows with pgtype
+			pkg[ImportSpec{Path: "github.com/jackc/pgx/v5/pgtype"}] = struct{}{}
+			pkg[ImportSpec{Path: "github.com/jackc/pgx/v5/pgxpool"}] = struct{}{}
 		}
 	default:
-		std = append(std, ImportSpec{Path: "database/sql"})
-		if i.Options.EmitMockExecutor {
-			// reflect is used by StubExecutor for argument comparison
-			std = append(std, ImportSpec{Path: "reflect"})
+		std["database/sql"] = struct{}{}
+		if i.Options.EmitPreparedQueries {
+			// sync guards the prepared statement cache
+			std["sync"] = struct{}{}
+		}
+		if i.Options.EmitReplayExecutor {
+			// fixtures are served through a database/sql driver
+			std["database/sql/driver"] = struct{}{}
+			std["io"] = struct{}{}
+		}
+		if usesMySQLDriver(i.Engine, i.Options) {
+			// mysql.MySQLError is used by IsRetryable to detect deadlocks
+			pkg[ImportSpec{Path: "github.com/go-sql-driver/mysql"}] = struct{}{}
 		}
 	}
 
-	sort.Slice(std, func(i, j int) bool { return std[i].Path < std[j].Path })
-	sort.Slice(pkg, func(i, j int) bool { return pkg[i].Path < pkg[j].Path })
-	return fileImports{Std: std, Dep: pkg}
+	return sortedImports(std, pkg)
 }
 
 var stdlibTypes = map[string]string{
@@ -305,12 +347,16 @@ func buildImports(options *opts.Options, queries []Query, outputFile OutputFile,
 }
 
 func (i *importer) interfaceImports() fileImports {
-	std, pkg := buildImports(i.Options, i.Queries, OutputFileInterface, func(name string) bool {
-		for _, q := range i.Queries {
+	var gq []Query
+	for _, q := range i.Queries {
+		if q.HasFacadeMethod() {
+			gq = append(gq, q)
+		}
+	}
+
+	std, pkg := buildImports(i.Options, gq, OutputFileInterface, func(name string) bool {
+		for _, q := range gq {
 			if q.hasRetType() {
-				if usesBatch([]Query{q}) {
-					continue
-				}
 				if hasPrefixIgnoringSliceAndPointerPrefix(q.Ret.Type(), name) {
 					return true
 				}
@@ -326,6 +372,11 @@ func (i *importer) interfaceImports() fileImports {
 
 	std["context"] = struct{}{}
 
+	// If queries are in a separate package, import the db package for DBTX and NewExecutor
+	if i.Options.OutputQueriesPackage != "" && i.Options.OutputQueriesPackage != i.Options.Package && i.Options.DbPackageImportPath != "" {
+		pkg[ImportSpec{Path: i.Options.DbPackageImportPath}] = struct{}{}
+	}
+
 	return sortedImports(std, pkg)
 }
 
@@ -336,6 +387,13 @@ func (i *importer) modelImports() fileImports {
 		std["fmt"] = struct{}{}
 		std["database/sql/driver"] = struct{}{}
 	}
+	for _, s := range i.Structs {
+		if s.HasSensitiveFields() {
+			std["fmt"] = struct{}{}
+			std["log/slog"] = struct{}{}
+			break
+		}
+	}
 
 	return sortedImports(std, pkg)
 }
@@ -357,6 +415,9 @@ func sortedImports(std map[string]struct{}, pkg map[ImportSpec]struct{}) fileImp
 func (i *importer) queryImports(filename string) fileImports {
 	var gq []Query
 	anyNonCopyFrom := false
+	anyMany := false
+	anyTimeout := false
+	anyPaginate := false
 	for _, query := range i.Queries {
 		if usesBatch([]Query{query}) {
 			continue
@@ -365,6 +426,13 @@ func (i *importer) queryImports(filename string) fileImports {
 			gq = append(gq, query)
 			if query.Cmd != metadata.CmdCopyFrom {
 				anyNonCopyFrom = true
+				anyTimeout = anyTimeout || usesTimeout([]Query{query})
+			}
+			if query.Cmd == metadata.CmdMany {
+				anyMany = true
+			}
+			if query.Paginate != nil {
+				anyPaginate = true
 			}
 		}
 	}
@@ -397,6 +465,14 @@ func (i *importer) queryImports(filename string) fileImports {
 					return true
 				}
 			}
+			// Check the cursor values of paginated queries
+			if q.Paginate != nil {
+				for _, c := range q.Paginate.Columns {
+					if hasPrefixIgnoringSliceAndPointerPrefix(c.Type, name) {
+						return true
+					}
+				}
+			}
 		}
 		return false
 	})
@@ -451,8 +527,24 @@ func (i *importer) queryImports(filename string) fileImports {
 	if anyNonCopyFrom {
 		std["context"] = struct{}{}
 	}
+	if anyMany {
+		std["iter"] = struct{}{}
+	}
+	if anyTimeout {
+		std["time"] = struct{}{}
+	}
+	if anyPaginate {
+		// cursors are base64 encoded JSON arrays of the keyset values
+		std["encoding/base64"] = struct{}{}
+		std["encoding/json"] = struct{}{}
+		std["fmt"] = struct{}{}
+	}
 
 	sqlpkg := parseDriver(i.Options.SqlPackage)
+	if usesSensitiveFields(gq, !sqlpkg.IsPGX()) {
+		std["fmt"] = struct{}{}
+		std["log/slog"] = struct{}{}
+	}
 	if sqlcSliceScan() && !sqlpkg.IsPGX() {
 		std["strings"] = struct{}{}
 	}
@@ -505,6 +597,13 @@ func (i *importer) copyfromImports() fileImports {
 
 	sqlpkg := parseDriver(i.Options.SqlPackage)
 	if sqlpkg.IsPGX() {
+		if usesTimeout(copyFromQueries) {
+			std["time"] = struct{}{}
+		}
+		if usesSensitiveFields(copyFromQueries, true) {
+			std["fmt"] = struct{}{}
+			std["log/slog"] = struct{}{}
+		}
 		switch sqlpkg {
 		case opts.SQLDriverPGXV4:
 			pkg[ImportSpec{Path: "github.com/jackc/pgx/v4"}] = struct{}{}
@@ -562,7 +661,23 @@ func (i *importer) batchImports() fileImports {
 	})
 
 	std["context"] = struct{}{}
-	std["errors"] = struct{}{}
+	if len(batchQueries) > 0 {
+		std["errors"] = struct{}{}
+	}
+	if i.Options.EmitBatchBuilder {
+		// Batch wraps the errors of queued queries, joins their SQL and
+		// picks the shortest of their timeouts
+		std["fmt"] = struct{}{}
+		std["strings"] = struct{}{}
+		std["time"] = struct{}{}
+	}
+	if usesTimeout(batchQueries) {
+		std["time"] = struct{}{}
+	}
+	if usesSensitiveFields(batchQueries, true) {
+		std["fmt"] = struct{}{}
+		std["log/slog"] = struct{}{}
+	}
 	sqlpkg := parseDriver(i.Options.SqlPackage)
 	switch sqlpkg {
 	case opts.SQLDriverPGXV4:
@@ -0,0 +1,111 @@
+package opts
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sqlc-dev/plugin-sdk-go/pattern"
+	"github.com/sqlc-dev/plugin-sdk-go/plugin"
+)
+
+// ColumnPattern selects columns with a "[catalog.][schema.]tablename.colname"
+// specifier, the same syntax used by Override.Column. Each part may contain
+// wildcards, e.g. `users.*` or `*.email`.
+type ColumnPattern struct {
+	ColumnName   *pattern.Match
+	TableCatalog *pattern.Match
+	TableSchema  *pattern.Match
+	TableRel     *pattern.Match
+}
+
+func parseColumnPattern(column, defaultSchema string) (*ColumnPattern, error) {
+	var p ColumnPattern
+	var err error
+	colParts := strings.Split(column, ".")
+	switch len(colParts) {
+	case 2:
+		if p.ColumnName, err = pattern.MatchCompile(colParts[1]); err != nil {
+			return nil, err
+		}
+		if p.TableRel, err = pattern.MatchCompile(colParts[0]); err != nil {
+			return nil, err
+		}
+		if p.TableSchema, err = pattern.MatchCompile(defaultSchema); err != nil {
+			return nil, err
+		}
+	case 3:
+		if p.ColumnName, err = pattern.MatchCompile(colParts[2]); err != nil {
+			return nil, err
+		}
+		if p.TableRel, err = pattern.MatchCompile(colParts[1]); err != nil {
+			return nil, err
+		}
+		if p.TableSchema, err = pattern.MatchCompile(colParts[0]); err != nil {
+			return nil, err
+		}
+	case 4:
+		if p.ColumnName, err = pattern.MatchCompile(colParts[3]); err != nil {
+			return nil, err
+		}
+		if p.TableRel, err = pattern.MatchCompile(colParts[2]); err != nil {
+			return nil, err
+		}
+		if p.TableSchema, err = pattern.MatchCompile(colParts[1]); err != nil {
+			return nil, err
+		}
+		if p.TableCatalog, err = pattern.MatchCompile(colParts[0]); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("`column` specifier %q is not the proper format, expected '[catalog.][schema.]tablename.colname'", column)
+	}
+	return &p, nil
+}
+
+func parseColumnPatterns(columns []string, req *plugin.GenerateRequest) ([]*ColumnPattern, error) {
+	schema := "public"
+	if req != nil && req.Catalog != nil {
+		schema = req.Catalog.DefaultSchema
+	}
+	patterns := make([]*ColumnPattern, 0, len(columns))
+	for _, column := range columns {
+		p, err := parseColumnPattern(column, schema)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+// Matches reports whether col belongs to a table and has a name selected by the pattern
+func (p *ColumnPattern) Matches(col *plugin.Column, defaultSchema string) bool {
+	if col == nil || col.Table == nil {
+		return false
+	}
+	schema := col.Table.Schema
+	if schema == "" {
+		schema = defaultSchema
+	}
+	if p.TableCatalog != nil && !p.TableCatalog.MatchString(col.Table.Catalog) {
+		return false
+	}
+	if !p.TableSchema.MatchString(schema) || !p.TableRel.MatchString(col.Table.Name) {
+		return false
+	}
+	name := col.Name
+	if col.OriginalName != "" {
+		name = col.OriginalName
+	}
+	return p.ColumnName.MatchString(name)
+}
+
+// MatchesAny reports whether any of patterns matches col
+func MatchesAny(patterns []*ColumnPattern, col *plugin.Column, defaultSchema string) bool {
+	for _, p := range patterns {
+		if p.Matches(col, defaultSchema) {
+			return true
+		}
+	}
+	return false
+}
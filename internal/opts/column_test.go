@@ -0,0 +1,38 @@
+package opts
+
+import (
+	"testing"
+
+	"github.com/sqlc-dev/plugin-sdk-go/plugin"
+)
+
+func TestColumnPattern(t *testing.T) {
+	email := &plugin.Column{Name: "email", Table: &plugin.Identifier{Name: "users"}}
+	authorEmail := &plugin.Column{Name: "email", Table: &plugin.Identifier{Schema: "blog", Name: "authors"}}
+
+	for _, test := range []struct {
+		column string
+		col    *plugin.Column
+		want   bool
+	}{
+		{"users.email", email, true},
+		{"users.name", email, false},
+		{"users.*", email, true},
+		{"*.email", authorEmail, false},
+		{"blog.*.email", authorEmail, true},
+		{"public.users.email", email, true},
+		{"users.email", &plugin.Column{Name: "email"}, false},
+	} {
+		p, err := parseColumnPattern(test.column, "public")
+		if err != nil {
+			t.Fatalf("parseColumnPattern(%q): %v", test.column, err)
+		}
+		if got := p.Matches(test.col, "public"); got != test.want {
+			t.Errorf("%q.Matches(%s.%s) = %v, want %v", test.column, test.col.GetTable().GetName(), test.col.Name, got, test.want)
+		}
+	}
+
+	if _, err := parseColumnPattern("email", "public"); err == nil {
+		t.Error("expected error for column without table")
+	}
+}
@@ -23,6 +23,15 @@ type Options struct {
 	EmitAllEnumValues           bool              `json:"emit_all_enum_values,omitempty" yaml:"emit_all_enum_values"`
 	EmitSqlAsComment            bool              `json:"emit_sql_as_comment,omitempty" yaml:"emit_sql_as_comment"`
 	EmitMockExecutor            bool              `json:"emit_mock_executor,omitempty" yaml:"emit_mock_executor"`
+	EmitQuerierFacade           bool              `json:"emit_querier_facade,omitempty" yaml:"emit_querier_facade"`
+	EmitPreparedQueries         bool              `json:"emit_prepared_queries,omitempty" yaml:"emit_prepared_queries"`
+	EmitLoggingExecutor         bool              `json:"emit_logging_executor,omitempty" yaml:"emit_logging_executor"`
+	EmitReplayExecutor          bool              `json:"emit_replay_executor,omitempty" yaml:"emit_replay_executor"`
+	EmitFakeExecutor            bool              `json:"emit_fake_executor,omitempty" yaml:"emit_fake_executor"`
+	EmitBatchBuilder            bool              `json:"emit_batch_builder,omitempty" yaml:"emit_batch_builder"`
+	EmitPipeline                bool              `json:"emit_pipeline,omitempty" yaml:"emit_pipeline"`
+	LogArgsAllowlist            []string          `json:"log_args_allowlist,omitempty" yaml:"log_args_allowlist"`
+	SensitiveColumns            []string          `json:"sensitive_columns,omitempty" yaml:"sensitive_columns"`
 	JsonTagsCaseStyle           string            `json:"json_tags_case_style,omitempty" yaml:"json_tags_case_style"`
 	Package                     string            `json:"package" yaml:"package"`
 	Out                         string            `json:"out" yaml:"out"`
@@ -48,7 +57,9 @@ type Options struct {
 	BuildTags                   string            `json:"build_tags,omitempty" yaml:"build_tags"`
 	Initialisms                 *[]string         `json:"initialisms,omitempty" yaml:"initialisms"`
 
-	InitialismsMap map[string]struct{} `json:"-" yaml:"-"`
+	InitialismsMap           map[string]struct{} `json:"-" yaml:"-"`
+	LogArgsAllowlistColumns  []*ColumnPattern    `json:"-" yaml:"-"`
+	SensitiveColumnsPatterns []*ColumnPattern    `json:"-" yaml:"-"`
 }
 
 type GlobalOptions struct {
@@ -100,6 +111,18 @@ func parseOpts(req *plugin.GenerateRequest) (*Options, error) {
 		}
 	}
 
+	allowlist, err := parseColumnPatterns(options.LogArgsAllowlist, req)
+	if err != nil {
+		return nil, fmt.Errorf("invalid options: log_args_allowlist: %w", err)
+	}
+	options.LogArgsAllowlistColumns = allowlist
+
+	sensitive, err := parseColumnPatterns(options.SensitiveColumns, req)
+	if err != nil {
+		return nil, fmt.Errorf("invalid options: sensitive_columns: %w", err)
+	}
+	options.SensitiveColumnsPatterns = sensitive
+
 	if options.SqlPackage != "" {
 		if err := validatePackage(options.SqlPackage); err != nil {
 			return nil, fmt.Errorf("invalid options: %s", err)
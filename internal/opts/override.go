@@ -3,7 +3,6 @@ package opts
 import (
 	"fmt"
 	"os"
-	"strings"
 
 	"github.com/sqlc-dev/plugin-sdk-go/pattern"
 	"github.com/sqlc-dev/plugin-sdk-go/plugin"
@@ -107,44 +106,14 @@ func (o *Override) parse(req *plugin.GenerateRequest) (err error) {
 
 	// validate Column
 	if o.Column != "" {
-		colParts := strings.Split(o.Column, ".")
-		switch len(colParts) {
-		case 2:
-			if o.ColumnName, err = pattern.MatchCompile(colParts[1]); err != nil {
-				return err
-			}
-			if o.TableRel, err = pattern.MatchCompile(colParts[0]); err != nil {
-				return err
-			}
-			if o.TableSchema, err = pattern.MatchCompile(schema); err != nil {
-				return err
-			}
-		case 3:
-			if o.ColumnName, err = pattern.MatchCompile(colParts[2]); err != nil {
-				return err
-			}
-			if o.TableRel, err = pattern.MatchCompile(colParts[1]); err != nil {
-				return err
-			}
-			if o.TableSchema, err = pattern.MatchCompile(colParts[0]); err != nil {
-				return err
-			}
-		case 4:
-			if o.ColumnName, err = pattern.MatchCompile(colParts[3]); err != nil {
-				return err
-			}
-			if o.TableRel, err = pattern.MatchCompile(colParts[2]); err != nil {
-				return err
-			}
-			if o.TableSchema, err = pattern.MatchCompile(colParts[1]); err != nil {
-				return err
-			}
-			if o.TableCatalog, err = pattern.MatchCompile(colParts[0]); err != nil {
-				return err
-			}
-		default:
-			return fmt.Errorf("Override `column` specifier %q is not the proper format, expected '[catalog.][schema.]tablename.colname'", o.Column)
+		p, err := parseColumnPattern(o.Column, schema)
+		if err != nil {
+			return fmt.Errorf("Override %w", err)
 		}
+		o.ColumnName = p.ColumnName
+		o.TableRel = p.TableRel
+		o.TableSchema = p.TableSchema
+		o.TableCatalog = p.TableCatalog
 	}
 
 	// validate GoType
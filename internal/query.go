@@ -2,7 +2,9 @@ package golang
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/sqlc-dev/plugin-sdk-go/metadata"
 	"github.com/sqlc-dev/plugin-sdk-go/plugin"
@@ -76,6 +78,23 @@ func (v QueryValue) Pairs() []Argument {
 	}
 }
 
+// MatcherNames returns one parameter name per query argument, in the order of
+// Args(), for the ExpectXxxMatching mock constructors.
+func (v QueryValue) MatcherNames() []string {
+	if v.isEmpty() {
+		return nil
+	}
+	if !v.IsStruct() {
+		return []string{escape(v.Name)}
+	}
+	fields := v.UniqueFields()
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = escape(toLowerCase(f.Name))
+	}
+	return names
+}
+
 func (v QueryValue) SlicePair() string {
 	if v.isEmpty() {
 		return ""
@@ -270,6 +289,35 @@ type Query struct {
 	Arg          QueryValue
 	// Used for :copyfrom
 	Table *plugin.Identifier
+	// Declared with a "-- @timeout <duration>" comment
+	Timeout time.Duration
+	// Declared with a "-- @paginate keyset(<columns>)" comment
+	Paginate *Paginate
+
+	// annotations holds the "-- @name value" comments of the query
+	annotations []string
+}
+
+// Paginate describes the keyset pagination of a :many query. Pages are read
+// by wrapping the query in a subquery that is filtered by the keyset columns
+// of the last row of the previous page.
+type Paginate struct {
+	Columns []KeysetColumn
+	// FirstSQL reads the first page, its last parameter is the row limit
+	FirstSQL string
+	// NextSQL reads the page following a cursor, its last parameters are the
+	// cursor values and the row limit
+	NextSQL string
+}
+
+// KeysetColumn is a column of a "-- @paginate keyset(...)" annotation
+type KeysetColumn struct {
+	// Name is the Go name of the cursor value, e.g. createdAt
+	Name string
+	// Type is the Go type of the column
+	Type string
+	// Value reads the column from the row named last, e.g. last.CreatedAt
+	Value string
 }
 
 func (q Query) hasRetType() bool {
@@ -278,6 +326,215 @@ func (q Query) hasRetType() bool {
 	return scanned && !q.Ret.isEmpty()
 }
 
+// HasFacadeMethod reports whether the query gets a method on the generated
+// Queries facade. Batch, copyfrom and execresult queries are only available
+// as query structs.
+func (q Query) HasFacadeMethod() bool {
+	switch q.Cmd {
+	case metadata.CmdOne, metadata.CmdMany, metadata.CmdExec, metadata.CmdExecRows, metadata.CmdExecLastId:
+		return true
+	}
+	return false
+}
+
+var (
+	writeStatementRe = regexp.MustCompile(`(?i)\b(INSERT|UPDATE|DELETE|MERGE|REPLACE)\b`)
+	lockingClauseRe  = regexp.MustCompile(`(?i)\b(FOR\s+(NO\s+KEY\s+)?UPDATE|FOR\s+(KEY\s+)?SHARE|LOCK\s+IN\s+SHARE\s+MODE)\b`)
+)
+
+// UsesPrimary reports whether a :one or :many query must run on the primary
+// database when reads are routed to replicas. That is the case for queries
+// that modify data (e.g. INSERT ... RETURNING), lock rows with FOR UPDATE or
+// FOR SHARE, or are annotated with a "-- @primary" comment.
+func (q Query) UsesPrimary() bool {
+	if q.Cmd != metadata.CmdOne && q.Cmd != metadata.CmdMany {
+		return false
+	}
+	if _, ok := q.annotation("primary"); ok {
+		return true
+	}
+	return writeStatementRe.MatchString(q.SQL) || lockingClauseRe.MatchString(q.SQL)
+}
+
+// TimeoutExpr returns Timeout as a Go expression, e.g. 500 * time.Millisecond
+func (q Query) TimeoutExpr() string {
+	units := []struct {
+		d    time.Duration
+		name string
+	}{
+		{time.Hour, "time.Hour"},
+		{time.Minute, "time.Minute"},
+		{time.Second, "time.Second"},
+		{time.Millisecond, "time.Millisecond"},
+		{time.Microsecond, "time.Microsecond"},
+		{time.Nanosecond, "time.Nanosecond"},
+	}
+	for _, u := range units {
+		if q.Timeout%u.d != 0 {
+			continue
+		}
+		if n := q.Timeout / u.d; n != 1 {
+			return fmt.Sprintf("%d * %s", n, u.name)
+		}
+		return u.name
+	}
+	return ""
+}
+
+var (
+	keysetRe  = regexp.MustCompile(`(?i)^keyset\s*\((.+)\)$`)
+	orderByRe = regexp.MustCompile(`(?is)\bORDER\s+BY\b(.*)$`)
+)
+
+// buildPaginate parses the "-- @paginate keyset(col [ASC|DESC], ...)"
+// annotation of gq. All columns are sorted in the same direction, which
+// defaults to the direction of the first column in the ORDER BY clause of the
+// query. The keyset columns are compared outside of the query, so its columns
+// must have unique names and the keyset columns cannot be NULL.
+func buildPaginate(gq Query, query *plugin.Query, engine string) (*Paginate, error) {
+	v, ok := gq.annotation("paginate")
+	if !ok {
+		return nil, nil
+	}
+	if gq.Cmd != metadata.CmdMany {
+		return nil, fmt.Errorf("%s: @paginate is only supported on :many queries", gq.MethodName)
+	}
+	if gq.Arg.HasSqlcSlices() {
+		return nil, fmt.Errorf("%s: @paginate is not supported on queries using sqlc.slice", gq.MethodName)
+	}
+	seen := map[string]bool{}
+	for i, c := range query.Columns {
+		if c.EmbedTable != nil {
+			return nil, fmt.Errorf("%s: @paginate is not supported on queries using sqlc.embed", gq.MethodName)
+		}
+		name := columnName(c, i)
+		if seen[strings.ToLower(name)] {
+			return nil, fmt.Errorf("%s: @paginate requires unique column names, the query returns %q more than once", gq.MethodName, name)
+		}
+		seen[strings.ToLower(name)] = true
+	}
+	m := keysetRe.FindStringSubmatch(v)
+	if m == nil {
+		return nil, fmt.Errorf("%s: invalid @paginate %q, expected keyset(column, ...)", gq.MethodName, v)
+	}
+
+	var (
+		p         Paginate
+		names     []string
+		direction string
+	)
+	for _, item := range strings.Split(m[1], ",") {
+		fields := strings.Fields(item)
+		if len(fields) == 0 || len(fields) > 2 {
+			return nil, fmt.Errorf("%s: invalid @paginate column %q", gq.MethodName, strings.TrimSpace(item))
+		}
+		name := fields[0]
+		if len(fields) == 2 {
+			dir := strings.ToUpper(fields[1])
+			if (dir != "ASC" && dir != "DESC") || (direction != "" && dir != direction) {
+				return nil, fmt.Errorf("%s: @paginate columns must all be sorted ASC or DESC", gq.MethodName)
+			}
+			direction = dir
+		}
+
+		col := -1
+		for i, c := range query.Columns {
+			if c.EmbedTable == nil && columnName(c, i) == name {
+				col = i
+				break
+			}
+		}
+		if col < 0 {
+			return nil, fmt.Errorf("%s: @paginate column %q is not returned by the query", gq.MethodName, name)
+		}
+		if !query.Columns[col].NotNull {
+			return nil, fmt.Errorf("%s: @paginate column %q can be NULL, keyset columns must be NOT NULL", gq.MethodName, name)
+		}
+		kc := KeysetColumn{Name: escape(argName(name)), Type: gq.Ret.Type(), Value: "last"}
+		if gq.Ret.IsStruct() {
+			f := gq.Ret.Struct.Fields[col]
+			kc.Type, kc.Value = f.Type, "last."+f.Name
+		}
+		p.Columns = append(p.Columns, kc)
+		names = append(names, name)
+	}
+	if direction == "" {
+		direction = "ASC"
+		if m := orderByRe.FindStringSubmatch(gq.SQL); m != nil {
+			first := regexp.MustCompile(`(?i)^\s*(\w+\.)?` + regexp.QuoteMeta(names[0]) + `\s+DESC\b`)
+			if first.MatchString(m[1]) {
+				direction = "DESC"
+			}
+		}
+	}
+
+	n := 0
+	for _, param := range query.Params {
+		n = max(n, int(param.Number))
+	}
+	// placeholder returns the i-th parameter following the query parameters
+	placeholder := func(i int) string {
+		if engine == "postgresql" {
+			return fmt.Sprintf("$%d", n+i)
+		}
+		return "?"
+	}
+
+	order := make([]string, len(names))
+	cursor := make([]string, len(names))
+	for i, name := range names {
+		order[i] = name + " " + direction
+		cursor[i] = placeholder(i + 1)
+	}
+	op := ">"
+	if direction == "DESC" {
+		op = "<"
+	}
+	inner := "SELECT * FROM (\n" + strings.TrimSuffix(strings.TrimSpace(gq.SQL), ";") + "\n) AS page\n"
+	where := fmt.Sprintf("WHERE (%s) %s (%s)\n", strings.Join(names, ", "), op, strings.Join(cursor, ", "))
+	orderBy := "ORDER BY " + strings.Join(order, ", ") + "\n"
+
+	p.FirstSQL = inner + orderBy + "LIMIT " + placeholder(1)
+	p.NextSQL = inner + where + orderBy + "LIMIT " + placeholder(len(names)+1)
+	return &p, nil
+}
+
+// annotationNames are the "-- @name" comments understood by the plugin. They
+// are left out of the generated doc comments, the same way sqlc drops its
+// "-- name:" comment
+var annotationNames = map[string]bool{
+	"@primary":  true,
+	"@timeout":  true,
+	"@paginate": true,
+}
+
+// splitAnnotations returns the "-- @name value" comments of a query, and the
+// comments left to document it once the known annotations are removed
+func splitAnnotations(comments []string) (docs, annotations []string) {
+	for _, c := range comments {
+		fields := strings.Fields(c)
+		if len(fields) > 0 && strings.HasPrefix(fields[0], "@") {
+			annotations = append(annotations, c)
+			if annotationNames[fields[0]] {
+				continue
+			}
+		}
+		docs = append(docs, c)
+	}
+	return docs, annotations
+}
+
+// annotation returns the value of the first "-- @name value" comment
+func (q Query) annotation(name string) (string, bool) {
+	for _, c := range q.annotations {
+		fields := strings.Fields(c)
+		if len(fields) > 0 && fields[0] == "@"+name {
+			return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(c), fields[0])), true
+		}
+	}
+	return "", false
+}
+
 func (q Query) TableIdentifierAsGoSlice() string {
 	escapedNames := make([]string, 0, 3)
 	for _, p := range []string{q.Table.Catalog, q.Table.Schema, q.Table.Name} {
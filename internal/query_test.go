@@ -0,0 +1,224 @@
+package golang
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sqlc-dev/plugin-sdk-go/metadata"
+	"github.com/sqlc-dev/plugin-sdk-go/plugin"
+)
+
+func TestQuery_UsesPrimary(t *testing.T) {
+	tests := []struct {
+		name     string
+		cmd      string
+		sql      string
+		comments []string
+		want     bool
+	}{
+		{
+			name: "select",
+			cmd:  metadata.CmdOne,
+			sql:  "SELECT * FROM users WHERE id = $1",
+			want: false,
+		},
+		{
+			name: "column names containing keywords",
+			cmd:  metadata.CmdMany,
+			sql:  "SELECT updated_at, deleted_at FROM users",
+			want: false,
+		},
+		{
+			name: "for update",
+			cmd:  metadata.CmdOne,
+			sql:  "SELECT * FROM users WHERE id = $1\nFOR UPDATE",
+			want: true,
+		},
+		{
+			name: "for no key update",
+			cmd:  metadata.CmdOne,
+			sql:  "SELECT * FROM users WHERE id = $1 for no key update",
+			want: true,
+		},
+		{
+			name: "insert returning",
+			cmd:  metadata.CmdOne,
+			sql:  "INSERT INTO users (name) VALUES ($1) RETURNING *",
+			want: true,
+		},
+		{
+			name:     "primary annotation",
+			cmd:      metadata.CmdMany,
+			sql:      "SELECT * FROM users",
+			comments: []string{" Lists users", " @primary"},
+			want:     true,
+		},
+		{
+			name: "exec",
+			cmd:  metadata.CmdExec,
+			sql:  "DELETE FROM users WHERE id = $1",
+			want: false,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			docs, annotations := splitAnnotations(tc.comments)
+			q := Query{Cmd: tc.cmd, SQL: tc.sql, Comments: docs, annotations: annotations}
+			if got := q.UsesPrimary(); got != tc.want {
+				t.Errorf("UsesPrimary() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestQuery_TimeoutExpr(t *testing.T) {
+	tests := []struct {
+		timeout time.Duration
+		want    string
+	}{
+		{500 * time.Millisecond, "500 * time.Millisecond"},
+		{time.Second, "time.Second"},
+		{90 * time.Second, "90 * time.Second"},
+		{2 * time.Hour, "2 * time.Hour"},
+		{1500 * time.Microsecond, "1500 * time.Microsecond"},
+	}
+	for _, tc := range tests {
+		if got := (Query{Timeout: tc.timeout}).TimeoutExpr(); got != tc.want {
+			t.Errorf("TimeoutExpr(%v) = %q, want %q", tc.timeout, got, tc.want)
+		}
+	}
+}
+
+func TestSplitAnnotations(t *testing.T) {
+	tests := []struct {
+		name            string
+		comments        []string
+		wantDocs        []string
+		wantAnnotations []string
+	}{
+		{
+			name:     "no annotations",
+			comments: []string{" Lists users.", ""},
+			wantDocs: []string{" Lists users.", ""},
+		},
+		{
+			name:            "primary",
+			comments:        []string{" Counts users.", " @primary"},
+			wantDocs:        []string{" Counts users."},
+			wantAnnotations: []string{" @primary"},
+		},
+		{
+			name:            "timeout",
+			comments:        []string{" Lists users.", " @timeout 5s"},
+			wantDocs:        []string{" Lists users."},
+			wantAnnotations: []string{" @timeout 5s"},
+		},
+		{
+			name:            "paginate",
+			comments:        []string{" @paginate keyset(created_at, id)", " Lists users by creation date."},
+			wantDocs:        []string{" Lists users by creation date."},
+			wantAnnotations: []string{" @paginate keyset(created_at, id)"},
+		},
+		{
+			name:            "unknown annotation is documented",
+			comments:        []string{" @unknown stays"},
+			wantDocs:        []string{" @unknown stays"},
+			wantAnnotations: []string{" @unknown stays"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			docs, annotations := splitAnnotations(tc.comments)
+			if !reflect.DeepEqual(docs, tc.wantDocs) {
+				t.Errorf("docs = %q, want %q", docs, tc.wantDocs)
+			}
+			if !reflect.DeepEqual(annotations, tc.wantAnnotations) {
+				t.Errorf("annotations = %q, want %q", annotations, tc.wantAnnotations)
+			}
+		})
+	}
+}
+
+func TestBuildPaginate(t *testing.T) {
+	query := &plugin.Query{
+		Columns: []*plugin.Column{{Name: "id", NotNull: true}, {Name: "name"}, {Name: "created_at", NotNull: true}},
+		Params:  []*plugin.Parameter{{Number: 1, Column: &plugin.Column{Name: "name"}}},
+	}
+	ret := QueryValue{Name: "i", Struct: &Struct{Name: "User", Fields: []Field{
+		{Name: "ID", Type: "int64"},
+		{Name: "Name", Type: "string"},
+		{Name: "CreatedAt", Type: "time.Time"},
+	}}}
+	newQuery := func(cmd, sql, annotation string) Query {
+		return Query{MethodName: "ListUsers", Cmd: cmd, SQL: sql, Ret: ret, annotations: []string{" " + annotation}}
+	}
+
+	t.Run("direction from order by", func(t *testing.T) {
+		gq := newQuery(metadata.CmdMany, "SELECT * FROM users WHERE name = $1 ORDER BY users.created_at DESC;", "@paginate keyset(created_at, id)")
+		p, err := buildPaginate(gq, query, "postgresql")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []KeysetColumn{
+			{Name: "createdAt", Type: "time.Time", Value: "last.CreatedAt"},
+			{Name: "id", Type: "int64", Value: "last.ID"},
+		}
+		if !reflect.DeepEqual(p.Columns, want) {
+			t.Errorf("Columns = %+v, want %+v", p.Columns, want)
+		}
+		inner := "SELECT * FROM (\nSELECT * FROM users WHERE name = $1 ORDER BY users.created_at DESC\n) AS page\n"
+		if want := inner + "ORDER BY created_at DESC, id DESC\nLIMIT $2"; p.FirstSQL != want {
+			t.Errorf("FirstSQL = %q, want %q", p.FirstSQL, want)
+		}
+		if want := inner + "WHERE (created_at, id) < ($2, $3)\nORDER BY created_at DESC, id DESC\nLIMIT $4"; p.NextSQL != want {
+			t.Errorf("NextSQL = %q, want %q", p.NextSQL, want)
+		}
+	})
+
+	t.Run("explicit direction", func(t *testing.T) {
+		gq := newQuery(metadata.CmdMany, "SELECT * FROM users WHERE name = ? ORDER BY id DESC", "@paginate keyset(id asc)")
+		p, err := buildPaginate(gq, query, "sqlite")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(p.NextSQL, "WHERE (id) > (?)\nORDER BY id ASC\nLIMIT ?") {
+			t.Errorf("unexpected NextSQL %q", p.NextSQL)
+		}
+	})
+
+	t.Run("no annotation", func(t *testing.T) {
+		gq := newQuery(metadata.CmdMany, "SELECT * FROM users", "Lists users")
+		if p, err := buildPaginate(gq, query, "postgresql"); p != nil || err != nil {
+			t.Errorf("buildPaginate() = %+v, %v, want nil", p, err)
+		}
+	})
+
+	for name, gq := range map[string]Query{
+		"not many":          newQuery(metadata.CmdOne, "SELECT * FROM users", "@paginate keyset(id)"),
+		"not keyset":        newQuery(metadata.CmdMany, "SELECT * FROM users", "@paginate offset"),
+		"unknown column":    newQuery(metadata.CmdMany, "SELECT * FROM users", "@paginate keyset(email)"),
+		"mixed directions":  newQuery(metadata.CmdMany, "SELECT * FROM users", "@paginate keyset(created_at desc, id asc)"),
+		"invalid direction": newQuery(metadata.CmdMany, "SELECT * FROM users", "@paginate keyset(id up)"),
+		"nullable column":   newQuery(metadata.CmdMany, "SELECT * FROM users", "@paginate keyset(name)"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := buildPaginate(gq, query, "postgresql"); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+
+	for name, columns := range map[string][]*plugin.Column{
+		"duplicate column": {{Name: "id", NotNull: true}, {Name: "ID", NotNull: true}},
+		"embedded table":   {{Name: "id", NotNull: true}, {Name: "users", EmbedTable: &plugin.Identifier{Name: "users"}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			gq := newQuery(metadata.CmdMany, "SELECT * FROM posts JOIN users ON users.id = posts.author_id", "@paginate keyset(id)")
+			if _, err := buildPaginate(gq, &plugin.Query{Columns: columns}, "postgresql"); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/sqlc-dev/plugin-sdk-go/metadata"
 	"github.com/sqlc-dev/plugin-sdk-go/plugin"
@@ -98,10 +99,11 @@ func buildStructs(req *plugin.GenerateRequest, options *opts.Options) []Struct {
 				}
 				addExtraGoStructTags(tags, req, options, column)
 				s.Fields = append(s.Fields, Field{
-					Name:    StructName(column.Name, options),
-					Type:    goType(req, options, column),
-					Tags:    tags,
-					Comment: column.Comment,
+					Name:      StructName(column.Name, options),
+					Type:      goType(req, options, column),
+					Tags:      tags,
+					Comment:   column.Comment,
+					Sensitive: isSensitiveColumn(req, options, column, s.Table),
 				})
 			}
 			structs = append(structs, s)
@@ -113,6 +115,19 @@ func buildStructs(req *plugin.GenerateRequest, options *opts.Options) []Struct {
 	return structs
 }
 
+// isSensitiveColumn reports whether column is selected by the
+// sensitive_columns option. Catalog columns don't always carry their table, so
+// table is used when the column has none.
+func isSensitiveColumn(req *plugin.GenerateRequest, options *opts.Options, column *plugin.Column, table *plugin.Identifier) bool {
+	if len(options.SensitiveColumnsPatterns) == 0 {
+		return false
+	}
+	if column.Table == nil && table != nil {
+		column = &plugin.Column{Name: column.Name, OriginalName: column.OriginalName, Table: table}
+	}
+	return opts.MatchesAny(options.SensitiveColumnsPatterns, column, req.Catalog.DefaultSchema)
+}
+
 type goColumn struct {
 	id int
 	*plugin.Column
@@ -202,7 +217,7 @@ func buildQueries(req *plugin.GenerateRequest, options *opts.Options, structs []
 			constantName = sdk.LowerTitle(query.Name)
 		}
 
-		comments := query.Comments
+		comments, annotations := splitAnnotations(query.Comments)
 		if options.EmitSqlAsComment {
 			if len(comments) == 0 {
 				comments = append(comments, query.Name)
@@ -227,6 +242,14 @@ func buildQueries(req *plugin.GenerateRequest, options *opts.Options, structs []
 			SQL:          query.Text,
 			Comments:     comments,
 			Table:        query.InsertIntoTable,
+			annotations:  annotations,
+		}
+		if v, ok := gq.annotation("timeout"); ok {
+			timeout, err := time.ParseDuration(v)
+			if err != nil || timeout <= 0 {
+				return nil, fmt.Errorf("%s: invalid @timeout %q, expected a positive duration such as 500ms", query.Name, v)
+			}
+			gq.Timeout = timeout
 		}
 		sqlpkg := parseDriver(options.SqlPackage)
 
@@ -327,6 +350,12 @@ func buildQueries(req *plugin.GenerateRequest, options *opts.Options, structs []
 			}
 		}
 
+		paginate, err := buildPaginate(gq, query, req.Settings.Engine)
+		if err != nil {
+			return nil, err
+		}
+		gq.Paginate = paginate
+
 		qs = append(qs, gq)
 	}
 	sort.Slice(qs, func(i, j int) bool { return qs[i].MethodName < qs[j].MethodName })
@@ -400,6 +429,7 @@ func columnsToStruct(req *plugin.GenerateRequest, options *opts.Options, name st
 		}
 		if c.embed == nil {
 			f.Type = goType(req, options, c.Column)
+			f.Sensitive = isSensitiveColumn(req, options, c.Column, nil)
 		} else {
 			f.Type = c.embed.modelType
 			f.EmbedFields = c.embed.fields
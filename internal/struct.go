@@ -26,6 +26,36 @@ func (s Struct) Type() string {
 	return s.Name
 }
 
+// HasSensitiveFields reports whether the struct masks any field, directly or
+// through an embedded model, in its String, GoString and LogValue methods.
+func (s Struct) HasSensitiveFields() bool {
+	for _, f := range s.Fields {
+		if f.Sensitive {
+			return true
+		}
+		for _, ef := range f.EmbedFields {
+			if ef.Sensitive {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UniqueFields returns the fields of the struct without duplicated names.
+func (s Struct) UniqueFields() []Field {
+	seen := map[string]struct{}{}
+	fields := make([]Field, 0, len(s.Fields))
+	for _, f := range s.Fields {
+		if _, found := seen[f.Name]; found {
+			continue
+		}
+		seen[f.Name] = struct{}{}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
 func StructName(name string, options *opts.Options) string {
 	if rename := options.Rename[name]; rename != "" {
 		return rename